@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// assembleContext creates a context from a cluster and a user selected by fzf,
+// for the kube config that only has clusters and users. The name is generated
+// from the cluster and user if not provided.
+func assembleContext(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config, name string) (string, error) {
+	if name != "" {
+		if _, ok := config.Contexts[name]; ok {
+			return "", fmt.Errorf("The cluster %q already exists", name)
+		}
+	}
+
+	clusters := make([]string, 0, len(config.Clusters))
+	for cluster := range config.Clusters {
+		clusters = append(clusters, cluster)
+	}
+	if len(clusters) == 0 {
+		return "", errors.New("No cluster to assemble")
+	}
+	sort.Strings(clusters)
+
+	users := make([]string, 0, len(config.AuthInfos))
+	for user := range config.AuthInfos {
+		users = append(users, user)
+	}
+	if len(users) == 0 {
+		return "", errors.New("No user to assemble")
+	}
+	sort.Strings(users)
+
+	idx, err := searchInteractive(configAccess, clusters)
+	if err != nil {
+		return "", fmt.Errorf("Search fzf: %w", err)
+	}
+	cluster := clusters[idx]
+
+	idx, err = searchInteractive(configAccess, users)
+	if err != nil {
+		return "", fmt.Errorf("Search fzf: %w", err)
+	}
+	user := users[idx]
+
+	if name == "" {
+		name = cluster
+		if user != cluster {
+			name = fmt.Sprintf("%s-%s", cluster, user)
+		}
+		if _, ok := config.Contexts[name]; ok {
+			return "", fmt.Errorf("The generated name %q already exists, please provide a name", name)
+		}
+	}
+
+	config.Contexts[name] = &clientcmdapi.Context{
+		Cluster:   cluster,
+		AuthInfo:  user,
+		Namespace: "default",
+	}
+	return name, nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const auditLogFilename = "switch.log"
+
+// disableAudit is set by the global flag "--no-audit", override the config
+// disableAudit.
+var disableAudit bool
+
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Command string    `json:"command"`
+	Context string    `json:"context"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+}
+
+func writeAuditLog(configAccess clientcmd.ConfigAccess, command, context, from, to string) error {
+	if disableAudit {
+		return nil
+	}
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return err
+	}
+	if cfg.DisableAudit {
+		return nil
+	}
+
+	entry := &auditEntry{
+		Time:    time.Now(),
+		Command: command,
+		Context: context,
+		From:    from,
+		To:      to,
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Encode audit log: %w", err)
+	}
+	data = append(data, '\n')
+
+	path := getStatePath(configAccess, auditLogFilename)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Open audit log: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("Write audit log: %w", err)
+	}
+	return nil
+}
+
+// recordAuditLog writes the audit log after switching. The switching is done,
+// so the failure is only warned.
+func recordAuditLog(out io.Writer, configAccess clientcmd.ConfigAccess, command, context, from, to string) {
+	err := writeAuditLog(configAccess, command, context, from, to)
+	if err != nil {
+		fmt.Fprintf(out, "%s: %v\n", color.YellowString("warning"), err)
+	}
+}
+
+func readAuditLog(configAccess clientcmd.ConfigAccess) ([]*auditEntry, error) {
+	path := getStatePath(configAccess, auditLogFilename)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []*auditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		entry := new(auditEntry)
+		err = json.Unmarshal(line, entry)
+		if err != nil {
+			return nil, fmt.Errorf("Decode audit log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Read audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+type logOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	tail    int
+	command string
+	context string
+	since   string
+	output  string
+
+	stdout io.Writer
+}
+
+func Log(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &logOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the switch audit log",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			switch opts.output {
+			case "table", "json":
+			default:
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			return opts.run()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.IntVarP(&opts.tail, "tail", "n", 20, "The number of latest entries to show, 0 means show all")
+	flags.StringVarP(&opts.command, "command", "c", "", "Only show the entries of the command (use or ns)")
+	flags.StringVar(&opts.context, "context", "", "Only show the entries of the cluster")
+	flags.StringVar(&opts.since, "since", "", "Only show the entries newer than a relative duration (such as 30m, 24h, 7d) or a time (such as 2006-01-02 15:04:05)")
+	flags.StringVarP(&opts.output, "output", "o", "table", "The output format, one of table and json (json lines)")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("table", "json"))
+
+	return cmd
+}
+
+func (o *logOptions) run() error {
+	var since time.Time
+	if o.since != "" {
+		var err error
+		since, err = parseSince(o.since, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := readAuditLog(o.configAccess)
+	if err != nil {
+		return err
+	}
+
+	var filtered []*auditEntry
+	for _, entry := range entries {
+		if o.command != "" && entry.Command != o.command {
+			continue
+		}
+		if o.context != "" && entry.Context != o.context {
+			continue
+		}
+		if !since.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	if o.tail > 0 && len(filtered) > o.tail {
+		filtered = filtered[len(filtered)-o.tail:]
+	}
+
+	if o.output == "json" {
+		encoder := json.NewEncoder(o.stdout)
+		for _, entry := range filtered {
+			err = encoder.Encode(entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(filtered) == 0 {
+		fmt.Fprintln(o.out, "No audit log to show")
+		return nil
+	}
+
+	rows := make([][]string, len(filtered))
+	for i, entry := range filtered {
+		rows[i] = []string{
+			entry.Time.Local().Format(time.DateTime),
+			entry.User,
+			entry.Command,
+			entry.Context,
+			entry.From,
+			entry.To,
+		}
+	}
+
+	ShowTable(o.out, []string{"time", "user", "command", "context", "from", "to"}, rows)
+	return nil
+}
+
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid since value %q, should be a duration or a time", s)
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	backupKubeConfigName = "kubeconfig"
+	backupConfigName     = "kubeswitch.yaml"
+	backupNsAliasName    = "ns_alias.yaml"
+)
+
+// The state files next to kubeconfig to backup, the caches are skipped since
+// they can be rebuilt.
+var backupStateFilenames = []string{
+	navStateFilename,
+	legacyLastContextFilename,
+	nsLastFilename,
+	nsStackFilename,
+	nsAppliedFilename,
+	nsHistoryFilename,
+	nsBookmarksFilename,
+	disabledContextsFilename,
+	contextsTemplateFilename,
+	auditLogFilename,
+}
+
+func Backup(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "backup -o FILE",
+		Short: "Backup the kube config and kubeswitch files into a tar.gz file",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if output == "" {
+				return errors.New("The --output flag is required")
+			}
+			return runBackup(out, configAccess, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The output tar.gz file")
+
+	return cmd
+}
+
+func Restore(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "restore FILE",
+		Short: "Restore the kube config and kubeswitch files from a backup",
+
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return withConfigLock(configAccess, func() error {
+				return runRestore(out, configAccess, args[0], force)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite the existing files")
+
+	return cmd
+}
+
+// getBackupKubeConfigName returns the archive name of the kube config at idx
+// of the loading precedence. The first one is named "kubeconfig", the others
+// are suffixed with their index, such as "kubeconfig.1".
+func getBackupKubeConfigName(idx int) string {
+	if idx == 0 {
+		return backupKubeConfigName
+	}
+	return fmt.Sprintf("%s.%d", backupKubeConfigName, idx)
+}
+
+// getBackupFiles returns the archive names and their local paths.
+func getBackupFiles(configAccess clientcmd.ConfigAccess) (map[string]string, error) {
+	aliasPath, err := getNsAliasPath(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{
+		backupConfigName:  getConfigPath(configAccess),
+		backupNsAliasName: aliasPath,
+	}
+	for i, path := range configAccess.GetLoadingPrecedence() {
+		files[getBackupKubeConfigName(i)] = path
+	}
+	for _, name := range backupStateFilenames {
+		files[name] = getStatePath(configAccess, name)
+	}
+	return files, nil
+}
+
+func isBackupKubeConfigName(name string) bool {
+	return name == backupKubeConfigName || strings.HasPrefix(name, backupKubeConfigName+".")
+}
+
+// readBackupKubeConfig reads the kube config to backup. The certificate files
+// are embedded so that the backup can be restored to other places, the
+// relative paths are resolved against the kube config's directory.
+func readBackupKubeConfig(configAccess clientcmd.ConfigAccess, path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := loadConfigFile(configAccess, path)
+	if err != nil {
+		return nil, fmt.Errorf("Load kube config %s: %w", path, err)
+	}
+	embedded, err := embedCertFiles(config, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if !embedded {
+		return data, nil
+	}
+
+	data, err = clientcmd.Write(*config)
+	if err != nil {
+		return nil, fmt.Errorf("Encode kube config %s: %w", path, err)
+	}
+	if isEncryptedConfig(path) {
+		transform, err := getConfigTransform(configAccess)
+		if err != nil {
+			return nil, err
+		}
+		data, err = runTransform(transform.Encrypt, path, data)
+		if err != nil {
+			return nil, fmt.Errorf("Encrypt kube config %s: %w", path, err)
+		}
+	}
+	return data, nil
+}
+
+func embedCertFiles(config *clientcmdapi.Config, dir string) (bool, error) {
+	var embedded bool
+	embed := func(path *string, data *[]byte) error {
+		if *path == "" {
+			return nil
+		}
+		certPath := *path
+		if !filepath.IsAbs(certPath) {
+			certPath = filepath.Join(dir, certPath)
+		}
+		content, err := os.ReadFile(certPath)
+		if err != nil {
+			return fmt.Errorf("Read certificate file: %w", err)
+		}
+		*path = ""
+		*data = content
+		embedded = true
+		return nil
+	}
+
+	for _, cluster := range config.Clusters {
+		err := embed(&cluster.CertificateAuthority, &cluster.CertificateAuthorityData)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, authInfo := range config.AuthInfos {
+		err := embed(&authInfo.ClientCertificate, &authInfo.ClientCertificateData)
+		if err != nil {
+			return false, err
+		}
+		err = embed(&authInfo.ClientKey, &authInfo.ClientKeyData)
+		if err != nil {
+			return false, err
+		}
+	}
+	return embedded, nil
+}
+
+func runBackup(out io.Writer, configAccess clientcmd.ConfigAccess, output string) error {
+	files, err := getBackupFiles(configAccess)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("Open backup file: %w", err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	names := getSortedKeys(files)
+	var count int
+	for _, name := range names {
+		var data []byte
+		if isBackupKubeConfigName(name) {
+			data, err = readBackupKubeConfig(configAccess, files[name])
+		} else {
+			data, err = os.ReadFile(files[name])
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("Read %s: %w", name, err)
+		}
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("Write tar header: %w", err)
+		}
+		_, err = tarWriter.Write(data)
+		if err != nil {
+			return fmt.Errorf("Write tar file: %w", err)
+		}
+		count++
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Close tar writer: %w", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Close gzip writer: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("Close backup file: %w", err)
+	}
+
+	fmt.Fprintf(out, "Backup %d files to %s\n", count, output)
+	return nil
+}
+
+func runRestore(out io.Writer, configAccess clientcmd.ConfigAccess, path string, force bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Open backup file: %w", err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("Read gzip: %w", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	// Read all files before writing, an invalid archive should not leave the
+	// state half restored.
+	contents := make(map[string][]byte)
+	precedence := configAccess.GetLoadingPrecedence()
+	knownNames := []string{backupConfigName, backupNsAliasName}
+	knownNames = append(knownNames, backupStateFilenames...)
+	for i := range precedence {
+		knownNames = append(knownNames, getBackupKubeConfigName(i))
+	}
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("Read tar: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return fmt.Errorf("Invalid backup entry %q, should be a regular file", header.Name)
+		}
+		if !slices.Contains(knownNames, header.Name) {
+			if isBackupKubeConfigName(header.Name) {
+				return fmt.Errorf("The backup entry %q has no kube config path to restore to, the loading precedence only has %d files", header.Name, len(precedence))
+			}
+			return fmt.Errorf("Invalid backup entry %q", header.Name)
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return fmt.Errorf("Read tar file %q: %w", header.Name, err)
+		}
+		contents[header.Name] = data
+	}
+	if _, ok := contents[backupKubeConfigName]; !ok {
+		return errors.New("Invalid backup, missing kube config")
+	}
+
+	// The alias path depends on the config, use the one to restore.
+	aliasPath, err := getRestoreNsAliasPath(configAccess, contents)
+	if err != nil {
+		return err
+	}
+	targets := map[string]string{
+		backupConfigName:  getConfigPath(configAccess),
+		backupNsAliasName: aliasPath,
+	}
+	for i, path := range precedence {
+		targets[getBackupKubeConfigName(i)] = path
+	}
+	for _, name := range backupStateFilenames {
+		targets[name] = getStatePath(configAccess, name)
+	}
+	for _, name := range getSortedKeys(contents) {
+		path := targets[name]
+		if _, err = os.Stat(path); err == nil && !force {
+			return fmt.Errorf("The file %s already exists, please use --force to overwrite it", path)
+		}
+	}
+
+	restoreFile := func(name, path string) error {
+		data, ok := contents[name]
+		if !ok {
+			return nil
+		}
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(path, data, 0600)
+		if err != nil {
+			return fmt.Errorf("Restore %s: %w", name, err)
+		}
+		return nil
+	}
+
+	for _, name := range getSortedKeys(contents) {
+		err = restoreFile(name, targets[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "Restore %d files from %s\n", len(contents), path)
+	return nil
+}
+
+// getRestoreNsAliasPath returns the alias path to restore to, with the config
+// in the backup if it has one.
+func getRestoreNsAliasPath(configAccess clientcmd.ConfigAccess, contents map[string][]byte) (string, error) {
+	data, ok := contents[backupConfigName]
+	if !ok {
+		return getNsAliasPath(configAccess)
+	}
+	cfg := new(Config)
+	err := yaml.Unmarshal(data, cfg)
+	if err != nil {
+		return "", fmt.Errorf("Decode backup config: %w", err)
+	}
+	return resolveNsAliasPath(configAccess, cfg)
+}
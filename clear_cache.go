@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type clearCacheOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	context string
+}
+
+func ClearCache(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &clearCacheOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "clear-cache [--context NAME]",
+		Short: "Clear the namespace cache",
+
+		Args: cobra.ExactArgs(0),
+
+		Hidden: true,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.context, "context", "", "Only clear the cache of the cluster")
+	_ = cmd.RegisterFlagCompletionFunc("context", completeContextFunc)
+
+	return cmd
+}
+
+func (o *clearCacheOptions) run() error {
+	if o.context == "" {
+		err := os.Remove(getStatePath(o.configAccess, nsCacheFilename))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Remove namespace cache: %w", err)
+		}
+		fmt.Fprintln(o.out, "Clear all cache done")
+		return nil
+	}
+
+	cache := make(map[string]*nsCacheItem)
+	err := readState(o.configAccess, nsCacheFilename, &cache)
+	if err != nil {
+		return err
+	}
+	if _, ok := cache[o.context]; !ok {
+		fmt.Fprintf(o.out, "No cache for cluster %s\n", nameColor().Sprint(o.context))
+		return nil
+	}
+	delete(cache, o.context)
+	err = writeState(o.configAccess, nsCacheFilename, cache)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.out, "Clear cache for cluster %s done\n", nameColor().Sprint(o.context))
+	return nil
+}
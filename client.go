@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// globalTimeout is set by the global flag "--timeout", it bounds every
+// operation touching the network.
+var globalTimeout = 30 * time.Second
+
+// newTimeoutContext returns a context with the timeout, bounded by the global
+// timeout. Zero timeout means only the global timeout.
+func newTimeoutContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if globalTimeout > 0 && (timeout <= 0 || globalTimeout < timeout) {
+		timeout = globalTimeout
+	}
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+func buildRestConfig(config *clientcmdapi.Config, name string) (*rest.Config, error) {
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(*config, name, &clientcmd.ConfigOverrides{}, nil)
+	return clientConfig.ClientConfig()
+}
+
+func newKubeClient(configAccess clientcmd.ConfigAccess, name string) (*kubernetes.Clientset, error) {
+	config, err := configAccess.GetStartingConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig, err := buildRestConfig(config, name)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig.Timeout = globalTimeout
+
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	if clientCfg, ok := cfg.Clients[name]; ok {
+		if clientCfg.Timeout > 0 {
+			restConfig.Timeout = clientCfg.Timeout
+		}
+		if clientCfg.QPS > 0 {
+			restConfig.QPS = clientCfg.QPS
+		}
+		if clientCfg.Burst > 0 {
+			restConfig.Burst = clientCfg.Burst
+		}
+		if restConfig.QPS > 0 && restConfig.Burst == 0 {
+			restConfig.Burst = rest.DefaultBurst
+		}
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Init kube client: %w", err)
+	}
+	return client, nil
+}
+
+func listServerNamespaces(ctx context.Context, client kubernetes.Interface, selector string) ([]string, error) {
+	nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("Get namespaces from server: %w", err)
+	}
+	items := make([]string, len(nsList.Items))
+	for i, ns := range nsList.Items {
+		items[i] = ns.Name
+	}
+	return items, nil
+}
+
+// filterAccessibleNamespaces returns the namespaces in which the user can list
+// pods, checked by SelfSubjectAccessReview concurrently.
+func filterAccessibleNamespaces(ctx context.Context, client kubernetes.Interface, items []string, workers int) ([]string, error) {
+	allowed := make([]bool, len(items))
+	errs := make([]error, len(items))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i, ns := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ns string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace: ns,
+						Verb:      "list",
+						Resource:  "pods",
+					},
+				},
+			}
+			result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			allowed[i] = result.Status.Allowed
+		}(i, ns)
+	}
+	wg.Wait()
+
+	var ret []string
+	for i, ns := range items {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("Review access of namespace %q: %w", ns, errs[i])
+		}
+		if allowed[i] {
+			ret = append(ret, ns)
+		}
+	}
+	return ret, nil
+}
+
+// getServerVersion is like the discovery ServerVersion, but respects ctx.
+func getServerVersion(ctx context.Context, client kubernetes.Interface) (string, error) {
+	data, err := client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("Get server version: %w", err)
+	}
+	var info version.Info
+	err = json.Unmarshal(data, &info)
+	if err != nil {
+		return "", fmt.Errorf("Decode server version: %w", err)
+	}
+	return info.GitVersion, nil
+}
+
+// countNodes lists the nodes in pages, the first page is a single node so
+// that the count comes from the remaining item count when the server
+// provides it, without transferring all the node objects.
+func countNodes(ctx context.Context, client kubernetes.Interface) (int, error) {
+	opts := metav1.ListOptions{Limit: 1}
+	var count int
+	for {
+		nodes, err := client.CoreV1().Nodes().List(ctx, opts)
+		if err != nil {
+			return 0, fmt.Errorf("List nodes: %w", err)
+		}
+		count += len(nodes.Items)
+		if nodes.Continue == "" {
+			return count, nil
+		}
+		if remaining := nodes.RemainingItemCount; remaining != nil {
+			return count + int(*remaining), nil
+		}
+		opts = metav1.ListOptions{Limit: 500, Continue: nodes.Continue}
+	}
+}
+
+func createNamespace(client kubernetes.Interface, name string) error {
+	ctx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	_, err := client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("Create namespace %q: %w", name, err)
+	}
+	return nil
+}
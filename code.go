@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// resolveCode resolves the short code printed by CI jobs to the kubeconfig in
+// store, by the config "remote.codeURL" or "remote.codeCommand". The "{code}"
+// in them will be replaced by the code.
+func resolveCode(configAccess clientcmd.ConfigAccess, code string) (*clientcmdapi.Config, error) {
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	remote := cfg.Remote
+	if remote == nil || (remote.CodeURL == "" && remote.CodeCommand == "") {
+		return nil, errors.New("The remote.codeURL or remote.codeCommand is not configured, cannot resolve code")
+	}
+
+	var data []byte
+	if remote.CodeURL != "" {
+		codeURL := strings.ReplaceAll(remote.CodeURL, "{code}", url.PathEscape(code))
+		data, err = fetchRemoteConfig(codeURL, remote.Headers)
+	} else {
+		data, err = runCodeCommand(remote.CodeCommand, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("Load config of code %q: %w", code, err)
+	}
+	return config, nil
+}
+
+func runCodeCommand(command, code string) ([]byte, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, fmt.Errorf("Parse code command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Invalid code command %q", command)
+	}
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, "{code}", code)
+	}
+
+	ctx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Run code command %q: %w", args[0], ctx.Err())
+		}
+		return nil, fmt.Errorf("Run code command %q: %w", args[0], err)
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("Empty config from code command")
+	}
+	return stdout.Bytes(), nil
+}
+
+// importCode imports the context resolved from code into config, returns the
+// name of the imported context.
+func importCode(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config, code, name string) (string, error) {
+	codeConfig, err := resolveCode(configAccess, code)
+	if err != nil {
+		return "", err
+	}
+
+	srcName := codeConfig.CurrentContext
+	if srcName == "" {
+		if len(codeConfig.Contexts) != 1 {
+			return "", fmt.Errorf("The config of code %q should have current-context or exactly one context", code)
+		}
+		for ctxName := range codeConfig.Contexts {
+			srcName = ctxName
+		}
+	}
+	if name == "" {
+		name = srcName
+	}
+	if _, ok := config.Contexts[name]; ok {
+		return "", fmt.Errorf("The cluster %q already exists, please provide another name", name)
+	}
+
+	err = mergeContext(config, codeConfig, srcName, name)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -1,48 +1,457 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
-func searchFzf(items []string) (int, error) {
+const defaultFzfOptions = "--height=40% --reverse"
+
+func getFzfArgs(configAccess clientcmd.ConfigAccess) ([]string, error) {
+	opts, ok := os.LookupEnv("KUBESWITCH_FZF_OPTS")
+	if !ok {
+		cfg, err := readConfig(configAccess)
+		if err != nil {
+			return nil, err
+		}
+		opts = defaultFzfOptions
+		if cfg.FzfOptions != nil {
+			opts = *cfg.FzfOptions
+		}
+	}
+
+	args, err := splitShellWords(opts)
+	if err != nil {
+		return nil, fmt.Errorf("Parse fzf options: %w", err)
+	}
+	return args, nil
+}
+
+// getPicker returns the interactive picker command from env KUBESWITCH_FZF,
+// such as "sk" or "fzf --height 40%", default is fzf.
+func getPicker() ([]string, error) {
+	picker := strings.TrimSpace(os.Getenv("KUBESWITCH_FZF"))
+	if picker == "" {
+		return []string{"fzf"}, nil
+	}
+	args, err := splitShellWords(picker)
+	if err != nil {
+		return nil, fmt.Errorf("Parse KUBESWITCH_FZF: %w", err)
+	}
+	if len(args) == 0 {
+		return []string{"fzf"}, nil
+	}
+	return args, nil
+}
+
+// isFzfCompatible reports whether the picker accepts the fzf options, such as
+// skim, which is a fzf clone.
+func isFzfCompatible(picker string) bool {
+	switch filepath.Base(picker) {
+	case "fzf", "sk", "fzf-tmux":
+		return true
+	}
+	return false
+}
+
+// searchInteractive lets the user pick one of items by the interactive picker
+// (fzf by default), returns the index of the selected item. The extraArgs are
+// only passed to fzf compatible pickers.
+func searchInteractive(configAccess clientcmd.ConfigAccess, items []string, extraArgs ...string) (int, error) {
+	idxs, err := pickLines(configAccess, items, items, false, extraArgs)
+	if err != nil {
+		return 0, err
+	}
+	return idxs[0], nil
+}
+
+// searchInteractiveMulti is like searchInteractive, but the user can select
+// multiple items, returns the indexes of the selected items.
+func searchInteractiveMulti(configAccess clientcmd.ConfigAccess, items []string, extraArgs ...string) ([]int, error) {
+	return pickLines(configAccess, items, items, true, extraArgs)
+}
+
+// searchInteractiveColumns is like searchInteractive, but each item has
+// multiple columns. The columns are aligned in the picker, and only the first
+// (primary) column is used for searching by fzf compatible pickers.
+func searchInteractiveColumns(configAccess clientcmd.ConfigAccess, rows [][]string, extraArgs ...string) (int, error) {
+	var widths []int
+	for _, row := range rows {
+		for i, col := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	fields := make([]string, len(rows))
+	plain := make([]string, len(rows))
+	for idx, row := range rows {
+		cols := make([]string, len(row))
+		for i, col := range row {
+			if i < len(row)-1 {
+				col += strings.Repeat(" ", widths[i]-len(col))
+			}
+			cols[i] = col
+		}
+		fields[idx] = strings.Join(cols, "\t")
+		plain[idx] = strings.Join(cols, "  ")
+	}
+
+	extraArgs = append(extraArgs, "--nth=1")
+	idxs, err := pickLines(configAccess, fields, plain, false, extraArgs)
+	if err != nil {
+		return 0, err
+	}
+	return idxs[0], nil
+}
+
+// pickLines runs the picker, the fields lines are tab delimited and passed to
+// fzf compatible pickers, other pickers get the plain lines.
+func pickLines(configAccess clientcmd.ConfigAccess, fields, plain []string, multi bool, extraArgs []string) ([]int, error) {
+	picker, err := getPicker()
+	if err != nil {
+		return nil, err
+	}
+	name := picker[0]
+	args := picker[1:]
+
+	fzfCompatible := isFzfCompatible(name)
+	if fzfCompatible {
+		fzfArgs, err := getFzfArgs(configAccess)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, fzfArgs...)
+		args = append(args, extraArgs...)
+		// Prefix a hidden index column to each item, so that the selection
+		// can be mapped back even if the displayed items are decorated.
+		args = append(args, "--delimiter=\t", "--with-nth=2..")
+		if multi {
+			args = append(args, "--multi")
+		}
+	}
+
 	var inputBuf bytes.Buffer
-	inputBuf.Grow(len(items))
-	for _, item := range items {
-		inputBuf.WriteString(item + "\n")
+	if fzfCompatible {
+		for idx, line := range fields {
+			inputBuf.WriteString(strconv.Itoa(idx) + "\t" + line + "\n")
+		}
+	} else {
+		for _, line := range plain {
+			inputBuf.WriteString(line + "\n")
+		}
 	}
 
 	var outputBuf bytes.Buffer
-	cmd := exec.Command("fzf")
+	cmd := exec.Command(name, args...)
 	cmd.Stdin = &inputBuf
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = &outputBuf
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, errors.New("fzf has not been installed in your system, please install it first")
+		if errors.Is(err, exec.ErrNotFound) || os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s has not been installed in your system, please install it first", name)
 		}
-		return 0, err
+		return nil, err
+	}
+
+	var idxs []int
+	for _, result := range strings.Split(strings.TrimSpace(outputBuf.String()), "\n") {
+		var idx int
+		if fzfCompatible {
+			idxStr, _, _ := strings.Cut(result, "\t")
+			idx, err = strconv.Atoi(idxStr)
+			if err != nil || idx < 0 || idx >= len(fields) {
+				return nil, fmt.Errorf("Unexpected %s result %q, please check your fzf options", name, result)
+			}
+		} else {
+			// Other pickers print the selected line as is.
+			result = strings.TrimSpace(result)
+			idx = slices.IndexFunc(plain, func(line string) bool {
+				return strings.TrimSpace(line) == result
+			})
+			if idx < 0 {
+				return nil, fmt.Errorf("Unexpected %s result %q", name, result)
+			}
+		}
+		idxs = append(idxs, idx)
+		if !multi {
+			break
+		}
+	}
+	return idxs, nil
+}
+
+func matchName(items []string, query string) (string, error) {
+	var prefixMatches []string
+	for _, item := range items {
+		if item == query {
+			return item, nil
+		}
+		if strings.HasPrefix(item, query) {
+			prefixMatches = append(prefixMatches, item)
+		}
+	}
+	switch len(prefixMatches) {
+	case 0:
+	case 1:
+		return prefixMatches[0], nil
+	default:
+		return "", ambiguousNameError(query, prefixMatches)
+	}
+
+	type scored struct {
+		item  string
+		score int
+	}
+	var matches []scored
+	for _, item := range items {
+		score, ok := fuzzyScore(item, query)
+		if ok {
+			matches = append(matches, scored{item: item, score: score})
+		}
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) == 1 || matches[0].score > matches[1].score {
+		return matches[0].item, nil
 	}
 
-	result := outputBuf.String()
-	result = strings.TrimSpace(result)
-	for idx, item := range items {
-		if item == result {
-			return idx, nil
+	var candidates []string
+	for _, match := range matches {
+		if match.score < matches[0].score {
+			break
 		}
+		candidates = append(candidates, match.item)
 	}
+	return "", ambiguousNameError(query, candidates)
+}
 
-	return 0, fmt.Errorf("cannot find %q from fzf result", result)
+func ambiguousNameError(query string, candidates []string) error {
+	const maxCandidates = 5
+	if len(candidates) > maxCandidates {
+		candidates = append(candidates[:maxCandidates], "...")
+	}
+	return fmt.Errorf("Ambiguous name %q, candidates: %s", query, strings.Join(candidates, ", "))
+}
+
+// fuzzyScore checks if query is a subsequence of item, consecutive matches and
+// matches at the start of words score higher.
+func fuzzyScore(item, query string) (int, bool) {
+	item = strings.ToLower(item)
+	query = strings.ToLower(query)
+
+	var score int
+	last := -1
+	pos := 0
+	for _, r := range query {
+		idx := strings.IndexRune(item[pos:], r)
+		if idx < 0 {
+			return 0, false
+		}
+		idx += pos
+
+		score++
+		if last >= 0 && idx == last+1 {
+			score += 5
+		}
+		if idx == 0 || strings.ContainsRune("-_./:@", rune(item[idx-1])) {
+			score += 3
+		}
+
+		last = idx
+		pos = idx + len(string(r))
+	}
+
+	return score, true
+}
+
+func findSimilarName(items []string, name string) string {
+	const maxDistance = 2
+
+	var similar string
+	minDistance := maxDistance + 1
+	for _, item := range items {
+		if item == name {
+			continue
+		}
+		distance := levenshtein(item, name)
+		if distance < minDistance {
+			similar = item
+			minDistance = distance
+		}
+	}
+	return similar
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func confirm(msg string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", msg)
+
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("Read confirm answer: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// expandPath expands the "~" in path, the relative path is based on dir.
+func expandPath(path, dir string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Get home dir: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+func prompt(msg string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s ", msg)
+
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("Read input: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	var escaped, inWord bool
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+
+		case r == '\\':
+			escaped = true
+			inWord = true
+
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("unexpected end of %q after escape character", s)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+type quietWriter struct {
+	out   io.Writer
+	quiet bool
+}
+
+func (w *quietWriter) Write(p []byte) (int, error) {
+	if w.quiet {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+func isTerminal(w io.Writer) bool {
+	if qw, ok := w.(*quietWriter); ok {
+		if qw.quiet {
+			return false
+		}
+		w = qw.out
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return term.IsTerminal(int(file.Fd()))
 }
 
 func nameColor() *color.Color {
@@ -66,3 +475,46 @@ func ShowTable(out io.Writer, titles []string, rows [][]string) {
 	table.AppendBulk(rows) // Add Bulk Data
 	table.Render()
 }
+
+func ShowPlain(out io.Writer, rows [][]string) {
+	for _, row := range rows {
+		fmt.Fprintln(out, strings.Join(row, "\t"))
+	}
+}
+
+func getEditor(configAccess clientcmd.ConfigAccess, editor string) ([]string, error) {
+	if editor == "" {
+		cfg, err := readConfig(configAccess)
+		if err != nil {
+			return nil, err
+		}
+		editor = cfg.Editor
+	}
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+	}
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		return nil, errors.New("Missing editor to edit file, please use flag --editor or env EDITOR to specify one")
+	}
+
+	args, err := splitShellWords(editor)
+	if err != nil {
+		return nil, fmt.Errorf("Parse editor command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Invalid editor command %q", editor)
+	}
+	return args, nil
+}
+
+func runEditor(editorArgs []string, path string) error {
+	args := append(editorArgs[1:len(editorArgs):len(editorArgs)], path)
+	cmd := exec.Command(editorArgs[0], args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -1,91 +1,139 @@
 package main
 
 import (
-	"context"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-func completeContextFunc(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+func getCompletionConfigAccess(cmd *cobra.Command) clientcmd.ConfigAccess {
+	patchOptions := clientcmd.NewDefaultPathOptions()
+	var kubeconfig string
+	if flag := cmd.Flag("kubeconfig"); flag != nil {
+		kubeconfig = flag.Value.String()
+	}
+	// Ignore the invalid path in completion, the env KUBESWITCH_KUBECONFIG is
+	// honored if no flag.
+	explicit, _ := applyKubeconfig(patchOptions, kubeconfig)
+	if !explicit {
+		var profile string
+		if flag := cmd.Flag("profile"); flag != nil {
+			profile = flag.Value.String()
+		}
+		// Ignore the invalid profile in completion.
+		_ = applyProfile(patchOptions, profile)
+	}
+	return newConfigAccess(patchOptions)
+}
+
+func completeContextFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) != 0 {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	patchOptions := clientcmd.NewDefaultPathOptions()
+	patchOptions := getCompletionConfigAccess(cmd)
 	config, err := patchOptions.GetStartingConfig()
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
+	names, err := filterContextNames(patchOptions, getContextNames(config))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// The description is separated by tab, cobra strips it for the shells
+	// not supporting descriptions (the "__completeNoDesc" command).
 	var ret []string
-	for name := range config.Contexts {
+	for _, name := range names {
 		if strings.HasPrefix(name, toComplete) {
-			ret = append(ret, name)
+			ret = append(ret, name+"\t"+getContextDescription(config, name))
 		}
 	}
-	sort.Strings(ret)
 
 	return ret, cobra.ShellCompDirectiveNoFileComp
 }
 
-func completeNamespaceFunc(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+func completeNamespaceFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) != 0 {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	patchOptions := clientcmd.NewDefaultPathOptions()
+	patchOptions := getCompletionConfigAccess(cmd)
 	config, err := patchOptions.GetStartingConfig()
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	alias, err := readNsAlias(patchOptions)
+	items, _, err := listNamespaces(patchOptions, config.CurrentContext)
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	var items []string
-	for prefix, nsList := range alias {
-		if strings.HasPrefix(config.CurrentContext, prefix) {
-			items = nsList
-			break
+	var ret []string
+	for _, item := range items {
+		if strings.HasPrefix(item, toComplete) {
+			ret = append(ret, item)
 		}
 	}
-	if len(items) == 0 {
-		filename := patchOptions.GetDefaultFilename()
-		restConfig, err := clientcmd.BuildConfigFromFlags("", filename)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
+	sort.Strings(ret)
 
-		client, err := kubernetes.NewForConfig(restConfig)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
+	return ret, cobra.ShellCompDirectiveNoFileComp
+}
 
-		ctx := context.Background()
-		nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveNoFileComp
+func getContextDescription(config *clientcmdapi.Config, name string) string {
+	ctx := config.Contexts[name]
+	ns := ctx.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	desc := ns
+	if cluster, ok := config.Clusters[ctx.Cluster]; ok && cluster.Server != "" {
+		desc += ", " + cluster.Server
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' {
+			return ' '
 		}
-		items = make([]string, len(nsList.Items))
-		for i, ns := range nsList.Items {
-			items[i] = ns.Name
+		return r
+	}, desc)
+}
+
+// completeValuesFunc completes the flag with the fixed values, such as the
+// output formats.
+func completeValuesFunc(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var ret []string
+		for _, value := range values {
+			if strings.HasPrefix(value, toComplete) {
+				ret = append(ret, value)
+			}
 		}
+		return ret, cobra.ShellCompDirectiveNoFileComp
 	}
+}
+
+// completeColumnsFunc completes the comma separated columns of list, the
+// columns already given are skipped.
+func completeColumnsFunc(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var prefix, last string
+	if idx := strings.LastIndex(toComplete, ","); idx >= 0 {
+		prefix, last = toComplete[:idx+1], toComplete[idx+1:]
+	} else {
+		last = toComplete
+	}
+	given := strings.Split(prefix, ",")
 
 	var ret []string
-	for _, item := range items {
-		if strings.HasPrefix(item, toComplete) {
-			ret = append(ret, item)
+	for _, column := range listColumns {
+		if slices.Contains(given, column) || !strings.HasPrefix(column, last) {
+			continue
 		}
+		ret = append(ret, prefix+column)
 	}
-	sort.Strings(ret)
-
-	return ret, cobra.ShellCompDirectiveNoFileComp
+	return ret, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type Config struct {
+	Editor string `yaml:"editor"`
+
+	FzfOptions *string `yaml:"fzfOptions"`
+
+	NsAlias string `yaml:"nsAlias"`
+
+	// How to combine the alias entries matching the cluster: first, union or
+	// longest (default).
+	NsAliasMerge string `yaml:"nsAliasMerge"`
+
+	DisableAudit bool `yaml:"disableAudit"`
+
+	Groups map[string][]string `yaml:"groups"`
+
+	// The kubeconfig path of each profile, used by "--profile".
+	Profiles map[string]string `yaml:"profiles"`
+
+	ContextPrefix string `yaml:"contextPrefix"`
+
+	// Restore the namespace last used in the cluster when switching to it.
+	RestoreNamespace bool `yaml:"restoreNamespace"`
+
+	// Which file receives the current-context when there are multiple
+	// kubeconfig files: "owner" (default) for the file defining the context,
+	// "first" for the first file.
+	CurrentContextFile string `yaml:"currentContextFile"`
+
+	Discover []DiscoverConfig `yaml:"discover"`
+
+	// The commands to decrypt and encrypt the encrypted kube config, such as
+	// "config.sops.yaml".
+	Transform *TransformConfig `yaml:"transform"`
+
+	// The remote service distributing kubeconfig.
+	Remote *RemoteConfig `yaml:"remote"`
+
+	// The namespace of the new context created by "set", it is a Go template
+	// that can reference the context name by "{{ .Name }}", such as
+	// "team-{{ .Name }}". Default is "default".
+	DefaultNamespace string `yaml:"defaultNamespace"`
+
+	// The regex patterns of production clusters, switching to them requires
+	// confirmation. The clusters with label "prod" are always production.
+	ProductionContexts []string `yaml:"productionContexts"`
+
+	// Warn the kube config and kubeswitch files with insecure permissions
+	// before each command.
+	WarnInsecure bool `yaml:"warnInsecure"`
+
+	// Warn when the server version is out of the skew from the reference
+	// version, checked by "use --info".
+	VersionSkew *VersionSkewConfig `yaml:"versionSkew"`
+
+	// The projects, each is a set of cluster and namespace pairs, switched by
+	// "kubeswitch project use NAME".
+	Projects map[string][]ProjectEntry `yaml:"projects"`
+
+	// The client settings for each context, used when requesting the server.
+	Clients map[string]ClientConfig `yaml:"clients"`
+}
+
+type TransformConfig struct {
+	Decrypt string `yaml:"decrypt"`
+	Encrypt string `yaml:"encrypt"`
+}
+
+type RemoteConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	TTL     time.Duration     `yaml:"ttl"`
+
+	// Resolve the code used by "use --code" to kubeconfig, by URL or command
+	// printing kubeconfig to stdout. The "{code}" will be replaced.
+	CodeURL     string `yaml:"codeURL"`
+	CodeCommand string `yaml:"codeCommand"`
+}
+
+type VersionSkewConfig struct {
+	// The reference version, such as the version of kubectl, "v1.29".
+	Reference string `yaml:"reference"`
+	// The max minor versions the server can differ, default is 1, same as
+	// the kubectl version skew policy.
+	MaxMinor int `yaml:"maxMinor"`
+}
+
+type ProjectEntry struct {
+	Context   string `yaml:"context"`
+	Namespace string `yaml:"namespace"`
+}
+
+type ClientConfig struct {
+	Timeout time.Duration `yaml:"timeout"`
+	QPS     float32       `yaml:"qps"`
+	Burst   int           `yaml:"burst"`
+}
+
+type DiscoverConfig struct {
+	Type   string `yaml:"type"`
+	Prefix string `yaml:"prefix"`
+
+	// For eks provider
+	Region  string `yaml:"region"`
+	Profile string `yaml:"profile"`
+
+	// For gke provider
+	Project string `yaml:"project"`
+}
+
+func getConfigPath(configAccess clientcmd.ConfigAccess) string {
+	filename := configAccess.GetDefaultFilename()
+	dir := filepath.Dir(filename)
+	return filepath.Join(dir, "kubeswitch.yaml")
+}
+
+func readConfig(configAccess clientcmd.ConfigAccess) (*Config, error) {
+	path := getConfigPath(configAccess)
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return new(Config), nil
+		}
+		return nil, fmt.Errorf("Open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	cfg := new(Config)
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("Decode config file: %w", err)
+	}
+
+	return cfg, nil
+}
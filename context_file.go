@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// readContextFile reads the context names from file, one name per line. The
+// empty lines and lines starting with "#" are ignored.
+func readContextFile(path string, config *clientcmdapi.Config) ([]string, error) {
+	names, missing, err := readContextFileNames(path, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Cannot find cluster %s in context file", formatNames(missing))
+	}
+	return names, nil
+}
+
+// readContextFileNames is like readContextFile, but returns the names not in
+// config rather than failing.
+func readContextFileNames(path string, config *clientcmdapi.Config) ([]string, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Open context file: %w", err)
+	}
+	defer file.Close()
+
+	var names []string
+	var missing []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") || slices.Contains(names, name) || slices.Contains(missing, name) {
+			continue
+		}
+		if _, ok := config.Contexts[name]; !ok {
+			missing = append(missing, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Read context file: %w", err)
+	}
+
+	if len(names) == 0 && len(missing) == 0 {
+		return nil, nil, errors.New("No cluster in context file")
+	}
+	return names, missing, nil
+}
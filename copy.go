@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type copyOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	src string
+	dst string
+
+	namespace string
+}
+
+func Copy(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &copyOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "copy SRC DST",
+		Short: "Copy a cluster to a new name",
+
+		Args: cobra.ExactArgs(2),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts.src = args[0]
+			opts.dst = args[1]
+			return withConfigLock(configAccess, opts.run)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "The namespace of the new cluster, default is the same as source")
+
+	return cmd
+}
+
+func (o *copyOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, ok := config.Contexts[o.src]
+	if !ok {
+		return fmt.Errorf("Cannot find cluster %q", o.src)
+	}
+	if o.src == o.dst {
+		return errors.New("The new name is the same as the source")
+	}
+	if _, ok = config.Contexts[o.dst]; ok {
+		return fmt.Errorf("Cluster %q already exists", o.dst)
+	}
+	if _, ok = config.Clusters[o.dst]; ok {
+		return fmt.Errorf("Cluster entry %q already exists", o.dst)
+	}
+	if _, ok = config.AuthInfos[o.dst]; ok {
+		return fmt.Errorf("User entry %q already exists", o.dst)
+	}
+
+	newCtx := ctx.DeepCopy()
+	if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+		config.Clusters[o.dst] = cluster.DeepCopy()
+		newCtx.Cluster = o.dst
+	}
+	if authInfo, ok := config.AuthInfos[ctx.AuthInfo]; ok {
+		config.AuthInfos[o.dst] = authInfo.DeepCopy()
+		newCtx.AuthInfo = o.dst
+	}
+	if o.namespace != "" {
+		newCtx.Namespace = o.namespace
+	}
+	config.Contexts[o.dst] = newCtx
+
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Modify config: %w", err)
+	}
+
+	fmt.Fprintf(o.out, "Copy cluster %q to %s\n", o.src, nameColor().Sprint(o.dst))
+	return nil
+}
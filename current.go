@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const defaultCurrentFormat = "{{.Context}}/{{.Namespace}}"
+
+type currentOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+	stdout       io.Writer
+
+	format        string
+	contextOnly   bool
+	namespaceOnly bool
+}
+
+type currentInfo struct {
+	Context   string
+	Namespace string
+	Cluster   string
+	User      string
+	Server    string
+}
+
+func Current(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &currentOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "current [--format FORMAT]",
+		Short: "Print the current cluster and namespace in one line, for shell prompts",
+
+		Args: cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.contextOnly && opts.namespaceOnly {
+				return errors.New("The --context-only flag cannot be used with --namespace-only")
+			}
+			if (opts.contextOnly || opts.namespaceOnly) && cmd.Flags().Changed("format") {
+				return errors.New("The --format flag cannot be used with --context-only or --namespace-only")
+			}
+			switch {
+			case opts.contextOnly:
+				opts.format = "{{.Context}}"
+			case opts.namespaceOnly:
+				opts.format = "{{.Namespace}}"
+			}
+			return opts.run()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.format, "format", defaultCurrentFormat, "The Go template to print, with fields Context, Namespace, Cluster, User and Server")
+	flags.BoolVar(&opts.contextOnly, "context-only", false, "Only print the current cluster")
+	flags.BoolVar(&opts.namespaceOnly, "namespace-only", false, "Only print the current namespace")
+
+	return cmd
+}
+
+func (o *currentOptions) run() error {
+	tmpl, err := template.New("current").Option("missingkey=error").Parse(o.format)
+	if err != nil {
+		return fmt.Errorf("Parse format: %w", err)
+	}
+
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if config.CurrentContext == "" {
+		return errors.New("No context selected")
+	}
+	ctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q", config.CurrentContext)
+	}
+
+	info := &currentInfo{
+		Context:   config.CurrentContext,
+		Namespace: ctx.Namespace,
+		Cluster:   ctx.Cluster,
+		User:      ctx.AuthInfo,
+	}
+	if info.Namespace == "" {
+		info.Namespace = "default"
+	}
+	if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+		info.Server = cluster.Server
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, info)
+	if err != nil {
+		return fmt.Errorf("Execute format: %w", err)
+	}
+	fmt.Fprintln(o.stdout, buf.String())
+	return nil
+}
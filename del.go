@@ -1,58 +1,299 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"slices"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 type delOptions struct {
 	configAccess clientcmd.ConfigAccess
 	out          io.Writer
+	stdout       io.Writer
 
-	name string
+	names  []string
+	output string
+
+	contextFile string
+	dryRun      bool
+
+	force bool
+
+	// Select the clusters by fzf, confirm before deleting unless yes.
+	interactive bool
+	yes         bool
 }
 
 func Del(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
-	opts := &delOptions{configAccess: configAccess, out: out}
+	opts := &delOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
 
 	cmd := &cobra.Command{
-		Use:   "del NAME",
-		Short: "Delete a cluster",
-
-		Args: cobra.ExactArgs(1),
+		Use:   "del [--context-file FILE] [-o json] [-y] [NAME...]",
+		Short: "Delete clusters",
 
 		ValidArgsFunction: completeContextFunc,
 
-		RunE: func(_ *cobra.Command, args []string) error {
-			opts.name = args[0]
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.output != "" && opts.output != "json" {
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			if opts.contextFile != "" {
+				if len(args) > 0 {
+					return errors.New("The --context-file flag cannot be used with cluster name")
+				}
+				return opts.run()
+			}
+			if len(args) == 0 {
+				opts.interactive = true
+				return opts.run()
+			}
+			opts.names = args
 			return opts.run()
 		},
 	}
 
+	flags := cmd.Flags()
+	flags.BoolVar(&opts.force, "force", false, "Force to delete the last remaining cluster")
+	flags.BoolVarP(&opts.yes, "yes", "y", false, "Do not ask for confirmation when deleting the clusters selected by fzf")
+	flags.StringVar(&opts.contextFile, "context-file", "", "Delete the clusters listed in the file, one name per line")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Only print the clusters to delete")
+	flags.StringVarP(&opts.output, "output", "o", "", "Print the summary of deletion to stdout, only support \"json\"")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("json"))
+
 	return cmd
 }
 
+// run selects the clusters without lock, since fzf and the confirmation may
+// take a long time, then deletes them with lock.
 func (o *delOptions) run() error {
 	config, err := o.configAccess.GetStartingConfig()
 	if err != nil {
 		return err
 	}
 
-	delete(config.Contexts, o.name)
-	delete(config.AuthInfos, o.name)
-	delete(config.Clusters, o.name)
-	if o.name == config.CurrentContext {
-		config.CurrentContext = ""
+	var names, notFound []string
+	if o.contextFile != "" {
+		names, notFound, err = readContextFileNames(o.contextFile, config)
+		if err != nil {
+			return err
+		}
+	} else if o.interactive {
+		names, err = o.selectInteractive(config)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, name := range o.names {
+			if slices.Contains(names, name) || slices.Contains(notFound, name) {
+				continue
+			}
+			if _, ok := config.Contexts[name]; !ok {
+				notFound = append(notFound, name)
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	if len(notFound) > 0 {
+		if o.output == "" {
+			return fmt.Errorf("Cannot find cluster %s", formatNames(notFound))
+		}
+		if len(names) == 0 {
+			return o.printResult(names, notFound)
+		}
+	}
+
+	if o.interactive && !o.yes && !o.dryRun {
+		ok, err := confirm(fmt.Sprintf("Delete %d clusters %s?", len(names), formatNames(names)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("Deletion cancelled")
+		}
 	}
 
-	err = clientcmd.ModifyConfig(o.configAccess, *config, true)
+	return withConfigLock(o.configAccess, func() error {
+		return o.apply(names, notFound)
+	})
+}
+
+// apply deletes the clusters, the config is reloaded in case it was modified
+// during selection.
+func (o *delOptions) apply(names, notFound []string) error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	names = slices.DeleteFunc(names, func(name string) bool {
+		if _, ok := config.Contexts[name]; !ok {
+			// Deleted by another process meanwhile.
+			notFound = append(notFound, name)
+			return true
+		}
+		return false
+	})
+	if len(names) == 0 {
+		if o.output == "" {
+			return fmt.Errorf("Cannot find cluster %s", formatNames(notFound))
+		}
+		return o.printResult(names, notFound)
+	}
+
+	var remaining int
+	for name := range config.Contexts {
+		if !slices.Contains(names, name) {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		if !o.force {
+			return fmt.Errorf("Cluster %s is the last one, deleting it will leave no cluster to use, please use --force to confirm", formatNames(names))
+		}
+		fmt.Fprintf(o.out, "%s: deleting the last cluster %s\n", color.YellowString("warning"), formatNames(names))
+	}
+
+	if o.dryRun {
+		for _, name := range names {
+			fmt.Fprintf(o.out, "Would delete cluster %q\n", name)
+		}
+		return o.printResult(names, notFound)
+	}
+
+	sources, err := loadConfigSources(o.configAccess)
+	if err != nil {
+		return err
+	}
+	files := getDefiningFiles(sources, names)
+
+	err = moveToTrash(o.configAccess, config, names)
+	if err != nil {
+		return err
+	}
+
+	var switched bool
+	for _, name := range names {
+		delete(config.Contexts, name)
+		delete(config.AuthInfos, name)
+		delete(config.Clusters, name)
+		if name == config.CurrentContext {
+			switched = true
+		}
+	}
+	if switched {
+		config.CurrentContext, err = o.selectReplacement(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = modifyConfig(o.configAccess, config)
 	if err != nil {
 		return fmt.Errorf("Modify config: %w", err)
 	}
-	fmt.Fprintf(o.out, "Delete cluster %q\n", o.name)
+	if len(sources) > 1 {
+		err = removeContextsFromSources(o.configAccess, names)
+		if err != nil {
+			return err
+		}
+	}
+	err = pruneDisabledContexts(o.configAccess, names)
+	if err != nil {
+		return fmt.Errorf("Prune disabled clusters: %w", err)
+	}
+	for _, name := range names {
+		fmt.Fprintf(o.out, "Delete cluster %q\n", name)
+	}
+	if len(sources) > 1 {
+		for _, path := range files {
+			fmt.Fprintf(o.out, "Modify file %s\n", path)
+		}
+	}
+	if len(names) > 1 {
+		fmt.Fprintf(o.out, "Deleted %d clusters\n", len(names))
+	}
+	if switched && config.CurrentContext != "" {
+		fmt.Fprintf(o.out, "Switch to cluster %s\n", nameColor().Sprint(config.CurrentContext))
+	}
+
+	return o.printResult(names, notFound)
+}
+
+func (o *delOptions) selectInteractive(config *clientcmdapi.Config) ([]string, error) {
+	items, err := filterContextNames(o.configAccess, getContextNames(config))
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("No cluster to delete")
+	}
+
+	idxs, err := searchInteractiveMulti(o.configAccess, items)
+	if err != nil {
+		return nil, fmt.Errorf("Search fzf: %w", err)
+	}
+	names := make([]string, len(idxs))
+	for i, idx := range idxs {
+		names[i] = items[idx]
+	}
+	return names, nil
+}
 
-	return nil
+// delResult is the summary of deletion, with dry run, Deleted lists the
+// clusters that would be deleted.
+type delResult struct {
+	Deleted  []string `json:"deleted"`
+	NotFound []string `json:"notFound"`
+	DryRun   bool     `json:"dryRun"`
+}
+
+func (o *delOptions) printResult(deleted, notFound []string) error {
+	if o.output != "json" {
+		return nil
+	}
+	result := delResult{Deleted: deleted, NotFound: notFound, DryRun: o.dryRun}
+	if result.Deleted == nil {
+		result.Deleted = []string{}
+	}
+	if result.NotFound == nil {
+		result.NotFound = []string{}
+	}
+	return json.NewEncoder(o.stdout).Encode(result)
+}
+
+// selectReplacement returns the cluster to use after deleting the current one,
+// prefer the last used cluster.
+func (o *delOptions) selectReplacement(config *clientcmdapi.Config) (string, error) {
+	if len(config.Contexts) == 0 {
+		return "", nil
+	}
+
+	state, err := readNavState(o.configAccess)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range state.recent(config.CurrentContext) {
+		if _, ok := config.Contexts[name]; ok {
+			return name, nil
+		}
+	}
+
+	return getContextNames(config)[0], nil
+}
+
+func formatNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
 }
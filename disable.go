@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const disabledContextsFilename = ".disabled_contexts"
+
+func Disable(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable NAME",
+		Short: "Disable a cluster, hide it from use and list without deleting",
+
+		Args: cobra.ExactArgs(1),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return withConfigLock(configAccess, func() error {
+				return setContextDisabled(out, configAccess, args[0], true)
+			})
+		},
+	}
+}
+
+func Enable(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable NAME",
+		Short: "Enable a disabled cluster",
+
+		Args: cobra.ExactArgs(1),
+
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			names, _ := readDisabledContexts(getCompletionConfigAccess(cmd))
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return withConfigLock(configAccess, func() error {
+				return setContextDisabled(out, configAccess, args[0], false)
+			})
+		},
+	}
+}
+
+func setContextDisabled(out io.Writer, configAccess clientcmd.ConfigAccess, name string, disabled bool) error {
+	names, err := readDisabledContexts(configAccess)
+	if err != nil {
+		return err
+	}
+	idx := slices.Index(names, name)
+
+	if !disabled {
+		if idx < 0 {
+			return fmt.Errorf("Cluster %q is not disabled", name)
+		}
+		names = slices.Delete(names, idx, idx+1)
+		err = writeState(configAccess, disabledContextsFilename, names)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Enable cluster %s\n", nameColor().Sprint(name))
+		return nil
+	}
+
+	config, err := configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[name]; !ok {
+		return fmt.Errorf("Cannot find cluster %q", name)
+	}
+	if idx >= 0 {
+		return fmt.Errorf("Cluster %q is already disabled", name)
+	}
+	names = append(names, name)
+	slices.Sort(names)
+	err = writeState(configAccess, disabledContextsFilename, names)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Disable cluster %s\n", nameColor().Sprint(name))
+	return nil
+}
+
+func readDisabledContexts(configAccess clientcmd.ConfigAccess) ([]string, error) {
+	var names []string
+	err := readState(configAccess, disabledContextsFilename, &names)
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// filterDisabledContexts drops the disabled clusters from names.
+func filterDisabledContexts(configAccess clientcmd.ConfigAccess, names []string) ([]string, error) {
+	disabled, err := readDisabledContexts(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	if len(disabled) == 0 {
+		return names, nil
+	}
+
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		if !slices.Contains(disabled, name) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
+
+// renameDisabledContext keeps the cluster disabled after renaming. The
+// overwritten cluster (rename --force) takes the status of the renamed one.
+func renameDisabledContext(configAccess clientcmd.ConfigAccess, oldName, newName string) error {
+	names, err := readDisabledContexts(configAccess)
+	if err != nil {
+		return err
+	}
+	oldIdx := slices.Index(names, oldName)
+	newIdx := slices.Index(names, newName)
+	if oldIdx < 0 && newIdx < 0 {
+		return nil
+	}
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return name == oldName || name == newName
+	})
+	if oldIdx >= 0 {
+		names = append(names, newName)
+		slices.Sort(names)
+	}
+	return writeState(configAccess, disabledContextsFilename, names)
+}
+
+// pruneDisabledContexts drops the deleted clusters from the disabled list.
+func pruneDisabledContexts(configAccess clientcmd.ConfigAccess, deleted []string) error {
+	names, err := readDisabledContexts(configAccess)
+	if err != nil {
+		return err
+	}
+	pruned := slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		return slices.Contains(deleted, name)
+	})
+	if len(pruned) == len(names) {
+		return nil
+	}
+	return writeState(configAccess, disabledContextsFilename, pruned)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type discoveredCluster struct {
+	name     string
+	location string
+}
+
+type discoverProvider interface {
+	listClusters() ([]discoveredCluster, error)
+	writeKubeconfig(cluster discoveredCluster, path string) error
+}
+
+var discoverProviders = map[string]func(cfg DiscoverConfig) discoverProvider{
+	"eks": func(cfg DiscoverConfig) discoverProvider { return &eksProvider{cfg: cfg} },
+	"gke": func(cfg DiscoverConfig) discoverProvider { return &gkeProvider{cfg: cfg} },
+}
+
+type discoverOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	types  []string
+	dryRun bool
+}
+
+func Discover(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &discoverOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "discover [TYPE...]",
+		Short: "Discover and import clusters from cloud providers",
+
+		Args:      cobra.OnlyValidArgs,
+		ValidArgs: []string{"eks", "gke"},
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts.types = args
+			if opts.dryRun {
+				return opts.run()
+			}
+			return withConfigLock(configAccess, opts.run)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "d", false, "Only show the discovered clusters, do not import them")
+
+	return cmd
+}
+
+func (o *discoverOptions) run() error {
+	cfg, err := readConfig(o.configAccess)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Discover) == 0 {
+		return errors.New("No discover provider in config file")
+	}
+
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "kubeswitch-discover-*")
+	if err != nil {
+		return fmt.Errorf("Create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var count int
+	// The clusters with the same name in different locations would overwrite
+	// each other, only the first one is imported.
+	discovered := make(map[string]string)
+	for idx, providerConfig := range cfg.Discover {
+		if !o.matchType(providerConfig.Type) {
+			continue
+		}
+		newProvider, ok := discoverProviders[providerConfig.Type]
+		if !ok {
+			return fmt.Errorf("Unknown discover provider type %q", providerConfig.Type)
+		}
+		provider := newProvider(providerConfig)
+
+		clusters, err := provider.listClusters()
+		if err != nil {
+			return fmt.Errorf("List %s clusters: %w", providerConfig.Type, err)
+		}
+
+		for _, cluster := range clusters {
+			name := providerConfig.Prefix + cluster.name
+			location := fmt.Sprintf("%s %s", providerConfig.Type, cluster.location)
+			if first, ok := discovered[name]; ok {
+				fmt.Fprintf(o.out, "%s: skip %s cluster %q, the name is already used by %s, please use different prefixes\n", color.YellowString("warning"), location, name, first)
+				continue
+			}
+			discovered[name] = location
+			if o.dryRun {
+				fmt.Fprintf(o.out, "Discover %s cluster %s\n", providerConfig.Type, nameColor().Sprint(name))
+				continue
+			}
+
+			path := filepath.Join(dir, fmt.Sprintf("%d-%s-%s", idx, cluster.location, cluster.name))
+			err = provider.writeKubeconfig(cluster, path)
+			if err != nil {
+				return fmt.Errorf("Get kubeconfig for %s cluster %q: %w", providerConfig.Type, cluster.name, err)
+			}
+
+			clusterConfig, err := clientcmd.LoadFromFile(path)
+			if err != nil {
+				return fmt.Errorf("Load kubeconfig for %s cluster %q: %w", providerConfig.Type, cluster.name, err)
+			}
+
+			action := "Import"
+			existing, ok := config.Contexts[name]
+			if ok {
+				action = "Update"
+			}
+			err = mergeContext(config, clusterConfig, clusterConfig.CurrentContext, name)
+			if err != nil {
+				return fmt.Errorf("Merge %s cluster %q: %w", providerConfig.Type, cluster.name, err)
+			}
+			if existing != nil {
+				// Only refresh the cluster and user, keep the namespace and
+				// extensions set by user.
+				config.Contexts[name].Namespace = existing.Namespace
+				config.Contexts[name].Extensions = existing.Extensions
+			}
+			fmt.Fprintf(o.out, "%s %s cluster %s\n", action, providerConfig.Type, nameColor().Sprint(name))
+			count++
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Modify config: %w", err)
+	}
+	fmt.Fprintf(o.out, "Imported %d clusters\n", count)
+
+	return nil
+}
+
+func (o *discoverOptions) matchType(providerType string) bool {
+	if len(o.types) == 0 {
+		return true
+	}
+	for _, t := range o.types {
+		if t == providerType {
+			return true
+		}
+	}
+	return false
+}
+
+type eksProvider struct {
+	cfg DiscoverConfig
+}
+
+func (p *eksProvider) listClusters() ([]discoveredCluster, error) {
+	args := append([]string{"eks", "list-clusters", "--output", "json"}, p.commonArgs()...)
+	data, err := runCloudCommand("aws", args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Clusters []string `json:"clusters"`
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, fmt.Errorf("Decode aws output: %w", err)
+	}
+
+	clusters := make([]discoveredCluster, len(result.Clusters))
+	for i, name := range result.Clusters {
+		clusters[i] = discoveredCluster{name: name, location: p.cfg.Region}
+	}
+	return clusters, nil
+}
+
+func (p *eksProvider) writeKubeconfig(cluster discoveredCluster, path string) error {
+	args := []string{"eks", "update-kubeconfig", "--name", cluster.name, "--kubeconfig", path}
+	args = append(args, p.commonArgs()...)
+	_, err := runCloudCommand("aws", args, nil)
+	return err
+}
+
+func (p *eksProvider) commonArgs() []string {
+	var args []string
+	if p.cfg.Region != "" {
+		args = append(args, "--region", p.cfg.Region)
+	}
+	if p.cfg.Profile != "" {
+		args = append(args, "--profile", p.cfg.Profile)
+	}
+	return args
+}
+
+type gkeProvider struct {
+	cfg DiscoverConfig
+}
+
+func (p *gkeProvider) listClusters() ([]discoveredCluster, error) {
+	args := append([]string{"container", "clusters", "list", "--format", "json"}, p.commonArgs()...)
+	data, err := runCloudCommand("gcloud", args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []struct {
+		Name     string `json:"name"`
+		Location string `json:"location"`
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, fmt.Errorf("Decode gcloud output: %w", err)
+	}
+
+	clusters := make([]discoveredCluster, len(result))
+	for i, c := range result {
+		clusters[i] = discoveredCluster{name: c.Name, location: c.Location}
+	}
+	return clusters, nil
+}
+
+func (p *gkeProvider) writeKubeconfig(cluster discoveredCluster, path string) error {
+	args := []string{"container", "clusters", "get-credentials", cluster.name, "--location", cluster.location}
+	args = append(args, p.commonArgs()...)
+	_, err := runCloudCommand("gcloud", args, []string{"KUBECONFIG=" + path})
+	return err
+}
+
+func (p *gkeProvider) commonArgs() []string {
+	var args []string
+	if p.cfg.Project != "" {
+		args = append(args, "--project", p.cfg.Project)
+	}
+	return args
+}
+
+func runCloudCommand(name string, args []string, env []string) ([]byte, error) {
+	ctx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+
+	var outputBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+
+	err := cmd.Run()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s has not been installed in your system, please install it first", name)
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Run %s: %w", name, ctx.Err())
+		}
+		return nil, fmt.Errorf("Run %s: %w", name, err)
+	}
+	return outputBuf.Bytes(), nil
+}
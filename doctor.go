@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const doctorDeleteItem = "<delete the context>"
+
+type doctorOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	fix bool
+}
+
+type doctorProblem struct {
+	context string
+	kind    string
+	ref     string
+}
+
+func Doctor(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &doctorOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "doctor [--fix]",
+		Short: "Check the contexts referring to missing cluster or user",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.fix, "fix", false, "Repair the broken contexts interactively, by re-pointing or deleting them")
+
+	return cmd
+}
+
+func (o *doctorOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	secure, err := o.checkPermissions()
+	if err != nil {
+		return err
+	}
+
+	problems := findDoctorProblems(config)
+	if config.CurrentContext != "" {
+		if _, ok := config.Contexts[config.CurrentContext]; !ok {
+			fmt.Fprintf(o.out, "%s: current context %q does not exist\n", color.YellowString("warning"), config.CurrentContext)
+		}
+	}
+	nsOK, err := o.checkNamespace(config)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		if !nsOK || !secure {
+			return &exitError{code: 1}
+		}
+		fmt.Fprintln(o.out, "No problem found")
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Fprintf(o.out, "%s: context %q refers to missing %s %q\n", color.YellowString("warning"), p.context, p.kind, p.ref)
+	}
+	if !o.fix {
+		return &exitError{code: 1}
+	}
+
+	// Select the fixes without lock, since fzf may take a long time, then
+	// apply them to the reloaded config with lock.
+	fixes := make(map[*doctorProblem]string, len(problems))
+	deleted := make(map[string]bool)
+	for _, p := range problems {
+		if deleted[p.context] {
+			// Already deleted when fixing the previous problem.
+			continue
+		}
+
+		var items []string
+		switch p.kind {
+		case "cluster":
+			items = getSortedKeys(config.Clusters)
+		case "user":
+			items = getSortedKeys(config.AuthInfos)
+		}
+		items = append(items, doctorDeleteItem)
+
+		fmt.Fprintf(o.out, "Select the %s for context %s\n", p.kind, nameColor().Sprint(p.context))
+		idx, err := searchInteractive(o.configAccess, items)
+		if err != nil {
+			return fmt.Errorf("Search fzf: %w", err)
+		}
+		fixes[p] = items[idx]
+		if items[idx] == doctorDeleteItem {
+			deleted[p.context] = true
+		}
+	}
+
+	return withConfigLock(o.configAccess, func() error {
+		return o.applyFixes(problems, fixes)
+	})
+}
+
+func (o *doctorOptions) applyFixes(problems []*doctorProblem, fixes map[*doctorProblem]string) error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	var deleted []string
+	for _, p := range problems {
+		item, ok := fixes[p]
+		if !ok {
+			continue
+		}
+		ctx, ok := config.Contexts[p.context]
+		if !ok {
+			continue
+		}
+
+		if item == doctorDeleteItem {
+			delete(config.Contexts, p.context)
+			deleted = append(deleted, p.context)
+			if config.CurrentContext == p.context {
+				config.CurrentContext = ""
+			}
+			fmt.Fprintf(o.out, "Delete context %q\n", p.context)
+			continue
+		}
+		switch p.kind {
+		case "cluster":
+			ctx.Cluster = item
+		case "user":
+			ctx.AuthInfo = item
+		}
+		fmt.Fprintf(o.out, "Point context %q to %s %q\n", p.context, p.kind, item)
+	}
+
+	if len(findDoctorProblems(config)) > 0 {
+		return errors.New("There are still problems after fixing")
+	}
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Modify config: %w", err)
+	}
+	if len(deleted) > 0 {
+		err = pruneDisabledContexts(o.configAccess, deleted)
+		if err != nil {
+			return fmt.Errorf("Prune disabled clusters: %w", err)
+		}
+	}
+	return nil
+}
+
+func findDoctorProblems(config *clientcmdapi.Config) []*doctorProblem {
+	var problems []*doctorProblem
+	for _, name := range getContextNames(config) {
+		ctx := config.Contexts[name]
+		if _, ok := config.Clusters[ctx.Cluster]; !ok {
+			problems = append(problems, &doctorProblem{context: name, kind: "cluster", ref: ctx.Cluster})
+		}
+		// A context without user is valid, such as using the in-cluster or
+		// anonymous credentials.
+		if _, ok := config.AuthInfos[ctx.AuthInfo]; ctx.AuthInfo != "" && !ok {
+			problems = append(problems, &doctorProblem{context: name, kind: "user", ref: ctx.AuthInfo})
+		}
+	}
+	return problems
+}
+
+func getSortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkPermissions checks the permissions of kube config and kubeswitch files.
+// With --fix, offers to tighten them.
+func (o *doctorOptions) checkPermissions() (bool, error) {
+	files, err := findInsecureFiles(o.configAccess)
+	if err != nil {
+		return false, err
+	}
+	if len(files) == 0 {
+		return true, nil
+	}
+	for _, file := range files {
+		fmt.Fprintln(o.out, file)
+	}
+	if !o.fix {
+		return false, nil
+	}
+
+	ok, err := confirm("Tighten the permissions?")
+	if err != nil || !ok {
+		return false, err
+	}
+	err = secureFiles(o.out, files)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkNamespace checks if the namespace of the current context still exists
+// on the server, skip if the server is unreachable or the user is not allowed
+// to list namespaces. With --fix, offers to switch to "default". Returns false
+// if the namespace is missing and not fixed.
+func (o *doctorOptions) checkNamespace(config *clientcmdapi.Config) (bool, error) {
+	ctx, ok := config.Contexts[config.CurrentContext]
+	if !ok || ctx.Namespace == "" || ctx.Namespace == "default" {
+		return true, nil
+	}
+
+	client, err := newKubeClient(o.configAccess, config.CurrentContext)
+	if err != nil {
+		return true, nil
+	}
+	reqCtx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+	items, err := listServerNamespaces(reqCtx, client, "")
+	if err != nil {
+		if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+			fmt.Fprintf(o.out, "Skip checking namespace, not allowed to list namespaces of cluster %q: %v\n", config.CurrentContext, err)
+		} else {
+			fmt.Fprintf(o.out, "Skip checking namespace, cluster %q is unreachable: %v\n", config.CurrentContext, err)
+		}
+		return true, nil
+	}
+	if slices.Contains(items, ctx.Namespace) {
+		return true, nil
+	}
+
+	fmt.Fprintf(o.out, "%s: namespace %q of current context %q does not exist on server\n", color.YellowString("warning"), ctx.Namespace, config.CurrentContext)
+	if !o.fix {
+		return false, nil
+	}
+
+	ok, err = confirm("Switch to namespace \"default\"?")
+	if err != nil || !ok {
+		return false, err
+	}
+	err = withConfigLock(o.configAccess, func() error {
+		config, err := o.configAccess.GetStartingConfig()
+		if err != nil {
+			return err
+		}
+		ctx, ok := config.Contexts[config.CurrentContext]
+		if !ok {
+			return fmt.Errorf("Cannot find context %q", config.CurrentContext)
+		}
+		ctx.Namespace = "default"
+		err = modifyConfig(o.configAccess, config)
+		if err != nil {
+			return fmt.Errorf("Modify config: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(o.out, "Switch to namespace %s\n", nameColor().Sprint("default"))
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type execOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	name      string
+	namespace string
+	args      []string
+}
+
+func Exec(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &execOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "exec NAME [-n namespace] -- COMMAND [ARGS...]",
+		Short: "Run a command with a cluster without switching",
+
+		Args: cobra.MinimumNArgs(2),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 1 {
+				return errors.New("The command should be provided after \"--\"")
+			}
+			opts.name = args[0]
+			opts.args = args[1:]
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Override the namespace of the cluster for the command")
+
+	return cmd
+}
+
+func (o *execOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	name := o.name
+	if _, ok := config.Contexts[name]; !ok {
+		match, err := matchName(getContextNames(config), name)
+		if err != nil {
+			return err
+		}
+		if match == "" {
+			return fmt.Errorf("Cannot find cluster %q", o.name)
+		}
+		name = match
+	}
+
+	execConfig := clientcmdapi.NewConfig()
+	err = mergeContext(execConfig, config, name, name)
+	if err != nil {
+		return err
+	}
+	execConfig.CurrentContext = name
+	if o.namespace != "" {
+		execConfig.Contexts[name].Namespace = o.namespace
+	}
+	// The temp config is in another dir, the relative paths would be broken.
+	err = clientcmdapi.FlattenConfig(execConfig)
+	if err != nil {
+		return fmt.Errorf("Flatten config: %w", err)
+	}
+
+	data, err := clientcmd.Write(*execConfig)
+	if err != nil {
+		return fmt.Errorf("Encode kube config: %w", err)
+	}
+	file, err := os.CreateTemp("", "kubeswitch-exec-*.yaml")
+	if err != nil {
+		return fmt.Errorf("Create temp file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Write temp file: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("Close temp file: %w", err)
+	}
+
+	cmd := exec.Command(o.args[0], o.args[1:]...)
+	cmd.Env = append(os.Environ(), clientcmd.RecommendedConfigPathEnvVar+"="+path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = runForeground(cmd)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			if code < 0 {
+				// Killed by signal.
+				code = 1
+			}
+			return &exitError{code: code}
+		}
+		return fmt.Errorf("Run command %q: %w", o.args[0], err)
+	}
+	return nil
+}
+
+// runForeground runs the command attached to the terminal. Ctrl-C is sent to
+// the command as well, so the interrupt is dropped here, to let kubeswitch
+// outlive the command and remove the temp config. The termination signals
+// sent to kubeswitch only are forwarded to the command for the same reason.
+func runForeground(cmd *exec.Cmd) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigCh:
+				if sig != os.Interrupt {
+					_ = cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return cmd.Wait()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+var (
+	jwtRegex   = regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)
+	tokenRegex = regexp.MustCompile(`^[A-Za-z0-9+/=_-]{32,}$`)
+)
+
+var sensitiveExecFlags = []string{"token", "password", "secret", "key"}
+
+func formatExec(authInfo *clientcmdapi.AuthInfo) string {
+	if authInfo == nil || authInfo.Exec == nil {
+		return ""
+	}
+	items := []string{authInfo.Exec.Command}
+	items = append(items, redactExecArgs(authInfo.Exec.Args)...)
+	return strings.Join(items, " ")
+}
+
+// redactExecArgs replaces the token-like args of exec plugin with "***".
+func redactExecArgs(args []string) []string {
+	items := make([]string, 0, len(args))
+	var redactNext bool
+	for _, arg := range args {
+		if redactNext {
+			items = append(items, "***")
+			redactNext = false
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			flag, _, hasValue := strings.Cut(arg, "=")
+			if isSensitiveExecFlag(flag) {
+				if hasValue {
+					items = append(items, flag+"=***")
+				} else {
+					items = append(items, arg)
+					redactNext = true
+				}
+				continue
+			}
+			items = append(items, arg)
+			continue
+		}
+		if jwtRegex.MatchString(arg) || tokenRegex.MatchString(arg) {
+			items = append(items, "***")
+			continue
+		}
+		items = append(items, arg)
+	}
+	return items
+}
+
+func isSensitiveExecFlag(flag string) bool {
+	flag = strings.ToLower(strings.TrimLeft(flag, "-"))
+	for _, word := range sensitiveExecFlags {
+		if strings.Contains(flag, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type exportOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+	stdout       io.Writer
+
+	name    string
+	all     bool
+	output  string
+	flatten bool
+
+	asCommands bool
+	reveal     bool
+}
+
+func Export(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &exportOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "export [--all -o FILE | --as-commands] [NAME]",
+		Short: "Export cluster as a standalone kube config",
+
+		Args: cobra.MaximumNArgs(1),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) >= 1 {
+				opts.name = args[0]
+			}
+			if opts.reveal && !opts.asCommands {
+				return errors.New("The --reveal flag can only be used with --as-commands")
+			}
+			if opts.all {
+				if opts.asCommands {
+					return errors.New("The --all flag cannot be used with --as-commands")
+				}
+				if opts.name != "" {
+					return errors.New("The --all flag cannot be used with cluster name")
+				}
+				if opts.output == "" {
+					return errors.New("The --output flag is required when using --all")
+				}
+				return opts.runAll()
+			}
+			return opts.run()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&opts.all, "all", "a", false, "Export all clusters into a tar.gz file, each cluster as a kube config file")
+	flags.StringVarP(&opts.output, "output", "o", "", "The output file, default is stdout")
+	flags.BoolVar(&opts.flatten, "flatten", false, "Embed the referenced certificate files into the exported config")
+	flags.BoolVar(&opts.asCommands, "as-commands", false, "Export as the kubectl config commands that recreate the cluster")
+	flags.BoolVar(&opts.reveal, "reveal", false, "Include the secrets such as token and client key in the commands rather than redacting them")
+
+	return cmd
+}
+
+func (o *exportOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	name := o.name
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name == "" {
+		return errors.New("No context selected")
+	}
+
+	var data []byte
+	if o.asCommands {
+		data, err = o.extractCommands(config, name)
+	} else {
+		data, err = o.extract(config, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.output == "" {
+		_, err = o.stdout.Write(data)
+		return err
+	}
+	err = os.WriteFile(o.output, data, 0600)
+	if err != nil {
+		return fmt.Errorf("Write export file: %w", err)
+	}
+	fmt.Fprintf(o.out, "Export cluster %s to %s\n", nameColor().Sprint(name), o.output)
+	return nil
+}
+
+func (o *exportOptions) runAll() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	names := getContextNames(config)
+	if len(names) == 0 {
+		return errors.New("No cluster to export")
+	}
+
+	file, err := os.OpenFile(o.output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("Open export file: %w", err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	now := time.Now()
+	filenames := make(map[string]struct{}, len(names))
+	var count int
+	for _, name := range names {
+		data, err := o.extract(config, name)
+		if err != nil {
+			fmt.Fprintf(o.out, "%s: skip cluster %q: %v\n", color.YellowString("warning"), name, err)
+			continue
+		}
+
+		// Different names like "a/b" and "a_b" may map to the same file,
+		// add a suffix to keep both of them.
+		filename := getExportFilename(name)
+		base := strings.TrimSuffix(filename, ".yaml")
+		for i := 2; ; i++ {
+			if _, ok := filenames[filename]; !ok {
+				break
+			}
+			filename = fmt.Sprintf("%s-%d.yaml", base, i)
+		}
+		filenames[filename] = struct{}{}
+
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    filename,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		})
+		if err != nil {
+			return fmt.Errorf("Write tar header: %w", err)
+		}
+		_, err = tarWriter.Write(data)
+		if err != nil {
+			return fmt.Errorf("Write tar file: %w", err)
+		}
+		count++
+	}
+	if count == 0 {
+		file.Close()
+		os.Remove(o.output)
+		return errors.New("No cluster to export")
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Close tar writer: %w", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Close gzip writer: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("Close export file: %w", err)
+	}
+
+	fmt.Fprintf(o.out, "Export %d clusters to %s\n", count, o.output)
+	return nil
+}
+
+func (o *exportOptions) extractConfig(config *clientcmdapi.Config, name string) (*clientcmdapi.Config, error) {
+	exportConfig := clientcmdapi.NewConfig()
+	err := mergeContext(exportConfig, config, name, name)
+	if err != nil {
+		return nil, err
+	}
+	exportConfig.CurrentContext = name
+
+	if o.flatten {
+		err = clientcmdapi.FlattenConfig(exportConfig)
+		if err != nil {
+			return nil, fmt.Errorf("Flatten config for %q: %w", name, err)
+		}
+	}
+	return exportConfig, nil
+}
+
+func (o *exportOptions) extract(config *clientcmdapi.Config, name string) ([]byte, error) {
+	exportConfig, err := o.extractConfig(config, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := clientcmd.Write(*exportConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Encode config for %q: %w", name, err)
+	}
+	return data, nil
+}
+
+func getExportFilename(name string) string {
+	// The context name may be an ARN like "arn:aws:eks:...:cluster/name".
+	name = strings.ReplaceAll(name, "/", "_")
+	return name + ".yaml"
+}
+
+// extractCommands returns the kubectl config commands that recreate the
+// cluster. The data fields have no flags in set-cluster and set-credentials,
+// so they are set by "kubectl config set" with base64 values.
+func (o *exportOptions) extractCommands(config *clientcmdapi.Config, name string) ([]byte, error) {
+	exportConfig, err := o.extractConfig(config, name)
+	if err != nil {
+		return nil, err
+	}
+	cluster := exportConfig.Clusters[name]
+	authInfo := exportConfig.AuthInfos[name]
+	ctx := exportConfig.Contexts[name]
+
+	secret := func(value string) string {
+		if o.reveal {
+			return value
+		}
+		return "REDACTED"
+	}
+
+	var buf strings.Builder
+	writeCommand := func(args ...string) {
+		buf.WriteString("kubectl config")
+		for _, arg := range args {
+			buf.WriteString(" " + quoteCommandArg(arg))
+		}
+		buf.WriteString("\n")
+	}
+	setData := func(path string, data []byte) {
+		writeCommand("set", path, base64.StdEncoding.EncodeToString(data))
+	}
+	// kubectl config set splits the path by dots, the data fields of a
+	// name with dots cannot be addressed.
+	hasData := len(cluster.CertificateAuthorityData) > 0 ||
+		len(authInfo.ClientCertificateData) > 0 || len(authInfo.ClientKeyData) > 0
+	if hasData && strings.Contains(name, ".") {
+		return nil, fmt.Errorf("Cannot export cluster %q as commands, the certificate data of a name with dots cannot be set by kubectl", name)
+	}
+
+	args := []string{"set-cluster", name, "--server=" + cluster.Server}
+	if cluster.CertificateAuthority != "" {
+		args = append(args, "--certificate-authority="+cluster.CertificateAuthority)
+	}
+	if cluster.InsecureSkipTLSVerify {
+		args = append(args, "--insecure-skip-tls-verify=true")
+	}
+	if cluster.TLSServerName != "" {
+		args = append(args, "--tls-server-name="+cluster.TLSServerName)
+	}
+	if cluster.ProxyURL != "" {
+		args = append(args, "--proxy-url="+cluster.ProxyURL)
+	}
+	writeCommand(args...)
+	if len(cluster.CertificateAuthorityData) > 0 {
+		setData("clusters."+name+".certificate-authority-data", cluster.CertificateAuthorityData)
+	}
+
+	args = []string{"set-credentials", name}
+	if authInfo.ClientCertificate != "" {
+		args = append(args, "--client-certificate="+authInfo.ClientCertificate)
+	}
+	if authInfo.ClientKey != "" {
+		args = append(args, "--client-key="+authInfo.ClientKey)
+	}
+	if authInfo.Token != "" {
+		args = append(args, "--token="+secret(authInfo.Token))
+	}
+	if authInfo.TokenFile != "" {
+		args = append(args, "--token-file="+authInfo.TokenFile)
+	}
+	if authInfo.Username != "" {
+		args = append(args, "--username="+authInfo.Username)
+	}
+	if authInfo.Password != "" {
+		args = append(args, "--password="+secret(authInfo.Password))
+	}
+	if exec := authInfo.Exec; exec != nil {
+		args = append(args, "--exec-command="+exec.Command)
+		if exec.APIVersion != "" {
+			args = append(args, "--exec-api-version="+exec.APIVersion)
+		}
+		execArgs := exec.Args
+		if !o.reveal {
+			execArgs = redactExecArgs(exec.Args)
+		}
+		for _, arg := range execArgs {
+			args = append(args, "--exec-arg="+arg)
+		}
+		for _, env := range exec.Env {
+			args = append(args, "--exec-env="+env.Name+"="+secret(env.Value))
+		}
+	}
+	if provider := authInfo.AuthProvider; provider != nil {
+		args = append(args, "--auth-provider="+provider.Name)
+		keys := make([]string, 0, len(provider.Config))
+		for key := range provider.Config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			args = append(args, "--auth-provider-arg="+key+"="+secret(provider.Config[key]))
+		}
+	}
+	writeCommand(args...)
+	if len(authInfo.ClientCertificateData) > 0 {
+		setData("users."+name+".client-certificate-data", authInfo.ClientCertificateData)
+	}
+	if len(authInfo.ClientKeyData) > 0 {
+		if o.reveal {
+			setData("users."+name+".client-key-data", authInfo.ClientKeyData)
+		} else {
+			buf.WriteString("# The client key data is redacted, use --reveal to include it\n")
+		}
+	}
+
+	writeCommand("set-context", name, "--cluster="+ctx.Cluster, "--user="+ctx.AuthInfo, "--namespace="+ctx.Namespace)
+	return []byte(buf.String()), nil
+}
+
+var safeCommandArgRegex = regexp.MustCompile(`^[a-zA-Z0-9_./:=@%+,-]+$`)
+
+func quoteCommandArg(arg string) string {
+	if safeCommandArgRegex.MatchString(arg) {
+		return arg
+	}
+	return shellQuote(arg)
+}
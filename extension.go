@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// contextExtensionName is the key of kubeswitch metadata in the context
+// extensions, so the metadata travels with the kubeconfig.
+const contextExtensionName = "kubeswitch"
+
+func getContextExtensions(ctx *clientcmdapi.Context) map[string]string {
+	values := make(map[string]string)
+	if ctx == nil {
+		return values
+	}
+	obj, ok := ctx.Extensions[contextExtensionName]
+	if !ok {
+		return values
+	}
+	unknown, ok := obj.(*runtime.Unknown)
+	if !ok {
+		return values
+	}
+	// Ignore the invalid extension, it may be modified by hand.
+	_ = json.Unmarshal(unknown.Raw, &values)
+	return values
+}
+
+func setContextExtensions(ctx *clientcmdapi.Context, values map[string]string) error {
+	if len(values) == 0 {
+		delete(ctx.Extensions, contextExtensionName)
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("Encode context extensions: %w", err)
+	}
+	if ctx.Extensions == nil {
+		ctx.Extensions = make(map[string]runtime.Object)
+	}
+	ctx.Extensions[contextExtensionName] = &runtime.Unknown{
+		Raw:         data,
+		ContentType: runtime.ContentTypeJSON,
+	}
+	return nil
+}
+
+func formatContextExtensions(ctx *clientcmdapi.Context) string {
+	values := getContextExtensions(ctx)
+	items := make([]string, 0, len(values))
+	for key, value := range values {
+		items = append(items, key+"="+value)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const contextsTemplateFilename = "contexts_template.yaml"
+
+// ContextsTemplate describes the parameterized contexts, such as:
+//
+//	templates:
+//	- name: "prod-{{ .region }}"
+//	  namespace: team
+//	  cluster:
+//	    server: "https://{{ .region }}.example.com"
+//	  user:
+//	    token: "..."
+//	  params:
+//	  - region: us-east-1
+//	  - region: eu-west-1
+//
+// The cluster and user are the same as kube config, all the strings in them
+// are Go templates rendered with each params.
+type ContextsTemplate struct {
+	Templates []ContextTemplate `yaml:"templates"`
+}
+
+type ContextTemplate struct {
+	Name      string              `yaml:"name"`
+	Namespace string              `yaml:"namespace"`
+	Cluster   yaml.Node           `yaml:"cluster"`
+	User      yaml.Node           `yaml:"user"`
+	Params    []map[string]string `yaml:"params"`
+}
+
+type genContextsOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	filename string
+	dryRun   bool
+}
+
+func GenContexts(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &genContextsOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "gen-contexts [-f FILE] [--dry-run]",
+		Short: "Generate clusters from the contexts template",
+
+		Args: cobra.NoArgs,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if opts.filename == "" {
+				opts.filename = getStatePath(configAccess, contextsTemplateFilename)
+			}
+			if opts.dryRun {
+				return opts.run()
+			}
+			return withConfigLock(configAccess, opts.run)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.filename, "file", "f", "", "The contexts template file, default is contexts_template.yaml next to kube config")
+	cmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "d", false, "Only show the clusters to generate")
+
+	return cmd
+}
+
+func (o *genContextsOptions) run() error {
+	data, err := os.ReadFile(o.filename)
+	if err != nil {
+		return fmt.Errorf("Read contexts template: %w", err)
+	}
+	var tmpl ContextsTemplate
+	err = yaml.Unmarshal(data, &tmpl)
+	if err != nil {
+		return fmt.Errorf("Decode contexts template: %w", err)
+	}
+	if len(tmpl.Templates) == 0 {
+		return errors.New("No template in contexts template file")
+	}
+
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	generated := make(map[string]struct{})
+	for idx, ctxTmpl := range tmpl.Templates {
+		for _, params := range ctxTmpl.Params {
+			genConfig, name, err := ctxTmpl.render(params)
+			if err != nil {
+				return fmt.Errorf("Render template %d: %w", idx, err)
+			}
+			if _, ok := generated[name]; ok {
+				return fmt.Errorf("Duplicate generated cluster %q", name)
+			}
+			generated[name] = struct{}{}
+
+			action := "Add"
+			existing, ok := config.Contexts[name]
+			if ok {
+				action = "Update"
+			}
+			if o.dryRun {
+				fmt.Fprintf(o.out, "Would %s cluster %s\n", strings.ToLower(action), nameColor().Sprint(name))
+				continue
+			}
+
+			err = mergeContext(config, genConfig, name, name)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				// The extensions such as the production label are managed
+				// by kubeswitch rather than the template, keep them.
+				config.Contexts[name].Extensions = existing.Extensions
+			}
+			fmt.Fprintf(o.out, "%s cluster %s\n", action, nameColor().Sprint(name))
+		}
+	}
+	if o.dryRun || len(generated) == 0 {
+		return nil
+	}
+
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Modify config: %w", err)
+	}
+	fmt.Fprintf(o.out, "Generated %d clusters\n", len(generated))
+	return nil
+}
+
+// render renders the template with params into a kube config, which has only
+// one context.
+func (t *ContextTemplate) render(params map[string]string) (*clientcmdapi.Config, string, error) {
+	name, err := renderTemplate(t.Name, params)
+	if err != nil {
+		return nil, "", err
+	}
+	if name == "" {
+		return nil, "", errors.New("Empty cluster name")
+	}
+	namespace, err := renderTemplate(t.Namespace, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cluster, err := renderTemplateNode(&t.Cluster, params)
+	if err != nil {
+		return nil, "", fmt.Errorf("Render cluster of %q: %w", name, err)
+	}
+	user, err := renderTemplateNode(&t.User, params)
+	if err != nil {
+		return nil, "", fmt.Errorf("Render user of %q: %w", name, err)
+	}
+
+	raw := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Config",
+		"clusters":   []any{map[string]any{"name": name, "cluster": cluster}},
+		"users":      []any{map[string]any{"name": name, "user": user}},
+		"contexts": []any{map[string]any{"name": name, "context": map[string]any{
+			"cluster":   name,
+			"user":      name,
+			"namespace": namespace,
+		}}},
+	}
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("Encode config of %q: %w", name, err)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("Load config of %q: %w", name, err)
+	}
+	return config, name, nil
+}
+
+func renderTemplate(text string, params map[string]string) (string, error) {
+	tmpl, err := template.New("context").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, params)
+	if err != nil {
+		return "", fmt.Errorf("Execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// renderTemplateNode renders the scalar values in the node tree, rather than
+// the serialized text, so that the params cannot break the YAML structure,
+// such as a value containing quotes or newlines.
+func renderTemplateNode(node *yaml.Node, params map[string]string) (any, error) {
+	if node.IsZero() {
+		return map[string]any{}, nil
+	}
+	rendered, err := renderNode(node, params)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	err = rendered.Decode(&value)
+	if err != nil {
+		return nil, fmt.Errorf("Decode rendered template: %w", err)
+	}
+	return value, nil
+}
+
+// renderNode returns a copy of node with the scalar values rendered, the
+// mapping keys are kept as is.
+func renderNode(node *yaml.Node, params map[string]string) (*yaml.Node, error) {
+	copied := *node
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value, err := renderTemplate(node.Value, params)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %w", node.Line, err)
+		}
+		copied.Value = value
+		return &copied, nil
+
+	case yaml.AliasNode:
+		alias, err := renderNode(node.Alias, params)
+		if err != nil {
+			return nil, err
+		}
+		copied.Alias = alias
+		return &copied, nil
+	}
+
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			copied.Content[i] = child
+			continue
+		}
+		rendered, err := renderNode(child, params)
+		if err != nil {
+			return nil, err
+		}
+		copied.Content[i] = rendered
+	}
+	return &copied, nil
+}
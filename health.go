@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	statusOk           = "ok"
+	statusUnreachable  = "unreachable"
+	statusUnauthorized = "unauthorized"
+
+	// Only for display, the context is not checked before the deadline.
+	statusUnchecked = "unchecked"
+)
+
+func checkContext(config *clientcmdapi.Config, name string, timeout time.Duration) string {
+	restConfig, err := buildRestConfig(config, name)
+	if err != nil {
+		return statusUnreachable
+	}
+
+	ctx, cancel := newTimeoutContext(context.Background(), timeout)
+	defer cancel()
+
+	return checkRestConfig(ctx, restConfig, timeout)
+}
+
+// checkContexts checks the contexts concurrently with a bounded worker pool.
+// Contexts sharing the same server and user are only checked once. The check
+// stops when the ctx is done, the unchecked contexts are left out of the
+// result, so they will not be taken as unreachable.
+func checkContexts(ctx context.Context, config *clientcmdapi.Config, names []string, workers int, timeout time.Duration, onDone func(done, total int)) map[string]string {
+	type checkGroup struct {
+		restConfig *rest.Config
+		names      []string
+	}
+
+	results := make(map[string]string, len(names))
+	groups := make(map[string]*checkGroup)
+	var keys []string
+	for _, name := range names {
+		restConfig, err := buildRestConfig(config, name)
+		if err != nil {
+			results[name] = statusUnreachable
+			continue
+		}
+
+		ctxConfig := config.Contexts[name]
+		key := restConfig.Host + "\x00" + ctxConfig.AuthInfo
+		group, ok := groups[key]
+		if !ok {
+			group = &checkGroup{restConfig: restConfig}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.names = append(group.names, name)
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+	tasks := make(chan *checkGroup)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var done int
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range tasks {
+				status := checkRestConfig(ctx, group.restConfig, timeout)
+				// Interrupted by the deadline rather than a real result.
+				checked := status != statusUnreachable || ctx.Err() == nil
+
+				lock.Lock()
+				if checked {
+					for _, name := range group.names {
+						results[name] = status
+					}
+				}
+				done++
+				if onDone != nil {
+					onDone(done, len(keys))
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		select {
+		case tasks <- groups[key]:
+		case <-ctx.Done():
+		}
+	}
+	close(tasks)
+	wg.Wait()
+
+	return results
+}
+
+func checkRestConfig(ctx context.Context, restConfig *rest.Config, timeout time.Duration) string {
+	if ctx.Err() != nil {
+		return statusUnreachable
+	}
+
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.Timeout = timeout
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return statusUnreachable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+	if err != nil {
+		if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+			return statusUnauthorized
+		}
+		return statusUnreachable
+	}
+
+	return statusOk
+}
+
+const healthCacheFilename = ".health_cache"
+
+type healthCacheItem struct {
+	Status string    `yaml:"status"`
+	Time   time.Time `yaml:"time"`
+}
+
+func readHealthCache(configAccess clientcmd.ConfigAccess) (map[string]*healthCacheItem, error) {
+	cache := make(map[string]*healthCacheItem)
+	err := readState(configAccess, healthCacheFilename, &cache)
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func saveHealthCache(configAccess clientcmd.ConfigAccess, status map[string]string) error {
+	cache, err := readHealthCache(configAccess)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for name, s := range status {
+		cache[name] = &healthCacheItem{Status: s, Time: now}
+	}
+	return writeState(configAccess, healthCacheFilename, cache)
+}
+
+func formatHealthCache(item *healthCacheItem) string {
+	if item == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s ago)", item.Status, duration.HumanDuration(time.Since(item.Time)))
+}
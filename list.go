@@ -1,23 +1,49 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"sort"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+var listColumns = []string{"name", "namespace", "cluster", "user", "server", "extensions", "exec", "status", "file"}
+
 type listOption struct {
 	configAccess clientcmd.ConfigAccess
 	out          io.Writer
+	stdout       io.Writer
+
+	output string
+
+	wide     bool
+	showExec bool
+	columns  []string
+
+	plain     bool
+	noHeaders bool
+
+	all bool
 
-	wide bool
+	check         bool
+	live          bool
+	checkTimeout  time.Duration
+	checkWorkers  int
+	checkDeadline time.Duration
 }
 
 func List(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
-	opts := &listOption{configAccess: configAccess, out: out}
+	opts := &listOption{configAccess: configAccess, out: out, stdout: os.Stdout}
 
 	cmd := &cobra.Command{
 		Use:   "list",
@@ -28,11 +54,38 @@ func List(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
 		ValidArgsFunction: completeContextFunc,
 
 		RunE: func(_ *cobra.Command, _ []string) error {
+			if opts.live {
+				opts.check = true
+			}
+			switch opts.output {
+			case "table", "json", "yaml":
+			default:
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			for _, column := range opts.columns {
+				if !slices.Contains(listColumns, column) {
+					return fmt.Errorf("Unsupported column %q, should be one of %s", column, strings.Join(listColumns, ", "))
+				}
+			}
 			return opts.run()
 		},
 	}
 
-	cmd.Flags().BoolVarP(&opts.wide, "wide", "w", false, "Show more info")
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.output, "output", "o", "table", "The output format, one of table, json and yaml")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("table", "json", "yaml"))
+	flags.BoolVarP(&opts.wide, "wide", "w", false, "Show more info, including the last known status from health cache (refreshed by ping)")
+	flags.StringSliceVar(&opts.columns, "columns", nil, "The columns to show, override --wide, such as name,namespace,server,user,status")
+	_ = cmd.RegisterFlagCompletionFunc("columns", completeColumnsFunc)
+	flags.BoolVar(&opts.showExec, "show-exec", false, "Show the exec plugin command of the clusters, with token-like args redacted")
+	flags.BoolVarP(&opts.plain, "plain", "p", false, "Show tab-separated values without headers, useful for piping")
+	flags.BoolVar(&opts.noHeaders, "no-headers", false, "Do not show the headers")
+	flags.BoolVarP(&opts.all, "all", "a", false, "Also show the disabled clusters")
+	flags.BoolVarP(&opts.check, "check", "c", false, "Check if the clusters are reachable")
+	flags.BoolVar(&opts.live, "live", false, "Force a fresh check rather than using the health cache, same as --check")
+	flags.DurationVar(&opts.checkTimeout, "check-timeout", 2*time.Second, "The timeout for checking each cluster")
+	flags.IntVar(&opts.checkWorkers, "check-workers", 10, "The max number of clusters to check concurrently")
+	flags.DurationVar(&opts.checkDeadline, "check-deadline", 30*time.Second, "The deadline for checking all clusters")
 
 	return cmd
 }
@@ -42,41 +95,251 @@ func (o *listOption) run() error {
 	if err != nil {
 		return err
 	}
-	if len(config.Contexts) == 0 {
+	remote, err := loadRemoteConfig(o.configAccess)
+	if err != nil {
+		return err
+	}
+	var remoteNames map[string]struct{}
+	if remote != nil {
+		config, remoteNames, err = withRemoteContexts(config, remote)
+		if err != nil {
+			return err
+		}
+	}
+
+	names, err := filterContextNames(o.configAccess, getContextNames(config))
+	if err != nil {
+		return err
+	}
+	if !o.all {
+		names, err = filterDisabledContexts(o.configAccess, names)
+		if err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
 		return errors.New("No cluster to show")
 	}
+	if len(names) != len(config.Contexts) {
+		// The config is read only here, so it is safe to drop the filtered out
+		// contexts.
+		contexts := make(map[string]*clientcmdapi.Context, len(names))
+		for _, name := range names {
+			contexts[name] = config.Contexts[name]
+		}
+		config.Contexts = contexts
+	}
+
+	sources, err := loadConfigSources(o.configAccess)
+	if err != nil {
+		return err
+	}
+	showFile := len(sources) > 1 || len(remoteNames) > 0
+	columns := o.getColumns(showFile)
+
+	var status map[string]string
+	var healthCache map[string]*healthCacheItem
+	if o.check {
+		status = o.checkStatus(config)
+		err = saveHealthCache(o.configAccess, status)
+		if err != nil {
+			return err
+		}
+	} else if slices.Contains(columns, "status") {
+		healthCache, err = readHealthCache(o.configAccess)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.output != "table" {
+		return o.printItems(config, names, status)
+	}
 
-	rows := make([][]string, 0, len(config.Contexts))
-	for name, ctx := range config.Contexts {
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		ctx := config.Contexts[name]
 		var cur string
 		if name == config.CurrentContext {
 			cur = "*"
 		}
 
-		row := []string{
-			cur,
-			name,
-			ctx.Namespace,
-		}
-		if o.wide {
-			cluster, ok := config.Clusters[ctx.Cluster]
-			if ok {
-				row = append(row, cluster.Server)
-			} else {
-				row = append(row, "")
+		row := []string{cur}
+		for _, column := range columns {
+			var value string
+			switch column {
+			case "name":
+				value = name
+			case "namespace":
+				value = ctx.Namespace
+			case "cluster":
+				value = ctx.Cluster
+			case "user":
+				value = ctx.AuthInfo
+			case "server":
+				if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+					value = cluster.Server
+				}
+			case "extensions":
+				value = formatContextExtensions(ctx)
+			case "exec":
+				value = formatExec(config.AuthInfos[ctx.AuthInfo])
+			case "status":
+				if o.check {
+					var ok bool
+					value, ok = status[name]
+					if !ok {
+						value = statusUnchecked
+					}
+				} else {
+					value = formatHealthCache(healthCache[name])
+				}
+			case "file":
+				if source := getContextSource(sources, name); source != nil {
+					value = source.path
+				} else if _, ok := remoteNames[name]; ok {
+					value = "<remote>"
+				}
 			}
+			row = append(row, value)
 		}
-
 		rows = append(rows, row)
 	}
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i][1] < rows[j][1]
-	})
 
-	titles := []string{"", "name", "namespace"}
-	if o.wide {
-		titles = append(titles, "server")
+	titles := append([]string{""}, columns...)
+	if o.plain {
+		ShowPlain(o.out, rows)
+		return nil
+	}
+	if o.noHeaders {
+		titles = nil
 	}
 	ShowTable(o.out, titles, rows)
+
+	if showFile {
+		if source := getCurrentContextSource(sources); source != nil {
+			fmt.Fprintf(o.out, "\nThe current-context is set by file %s\n", nameColor().Sprint(source.path))
+		}
+	}
 	return nil
 }
+
+// listItem is the structured output of list, the kubeswitch metadata are only
+// included when present.
+type listItem struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Server    string `json:"server" yaml:"server"`
+	Current   bool   `json:"current" yaml:"current"`
+
+	// The context extensions except the note.
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Note     string            `json:"note,omitempty" yaml:"note,omitempty"`
+	LastUsed *time.Time        `json:"lastUsed,omitempty" yaml:"lastUsed,omitempty"`
+
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// noteLabel is the context extension shown as note, set by
+// "kubeswitch set NAME --extension note=...".
+const noteLabel = "note"
+
+func (o *listOption) printItems(config *clientcmdapi.Config, names []string, status map[string]string) error {
+	lastUsed, err := getLastUsed(o.configAccess)
+	if err != nil {
+		return err
+	}
+
+	items := make([]*listItem, 0, len(names))
+	for _, name := range names {
+		ctx := config.Contexts[name]
+		item := &listItem{
+			Name:      name,
+			Namespace: ctx.Namespace,
+			Current:   name == config.CurrentContext,
+			Status:    status[name],
+		}
+		if _, ok := status[name]; o.check && !ok {
+			item.Status = statusUnchecked
+		}
+		if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+			item.Server = cluster.Server
+		}
+		labels := getContextExtensions(ctx)
+		item.Note = labels[noteLabel]
+		delete(labels, noteLabel)
+		if len(labels) > 0 {
+			item.Labels = labels
+		}
+		if t, ok := lastUsed[name]; ok {
+			item.LastUsed = &t
+		}
+		items = append(items, item)
+	}
+
+	if o.output == "yaml" {
+		encoder := yaml.NewEncoder(o.stdout)
+		encoder.SetIndent(2)
+		return encoder.Encode(items)
+	}
+	encoder := json.NewEncoder(o.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// getLastUsed returns the last time switching to or in each context, from the
+// audit log.
+func getLastUsed(configAccess clientcmd.ConfigAccess) (map[string]time.Time, error) {
+	entries, err := readAuditLog(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	lastUsed := make(map[string]time.Time)
+	for _, entry := range entries {
+		name := entry.Context
+		if entry.Command == "use" {
+			name = entry.To
+		}
+		if entry.Time.After(lastUsed[name]) {
+			lastUsed[name] = entry.Time
+		}
+	}
+	return lastUsed, nil
+}
+
+func (o *listOption) getColumns(showFile bool) []string {
+	if len(o.columns) > 0 {
+		return o.columns
+	}
+
+	columns := []string{"name", "namespace"}
+	if o.wide {
+		columns = append(columns, "server", "extensions")
+	}
+	if o.showExec {
+		columns = append(columns, "exec")
+	}
+	if o.check || o.wide {
+		columns = append(columns, "status")
+	}
+	if showFile {
+		columns = append(columns, "file")
+	}
+	return columns
+}
+
+func (o *listOption) checkStatus(config *clientcmdapi.Config) map[string]string {
+	ctx, cancel := newTimeoutContext(context.Background(), o.checkDeadline)
+	defer cancel()
+
+	var onDone func(done, total int)
+	if isTerminal(o.out) {
+		onDone = func(done, total int) {
+			fmt.Fprintf(o.out, "\rChecking clusters %d/%d", done, total)
+		}
+		defer fmt.Fprint(o.out, "\r\033[K")
+	}
+
+	names := getContextNames(config)
+	return checkContexts(ctx, config, names, o.checkWorkers, o.checkTimeout, onDone)
+}
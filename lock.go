@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	configLockFilename = ".kubeswitch.lock"
+
+	configLockTimeout = 10 * time.Second
+	configLockRetry   = 100 * time.Millisecond
+)
+
+var errConfigLocked = errors.New("The kube config is locked by another kubeswitch process, please try again later")
+
+// withConfigLock runs fn with an advisory file lock, to prevent concurrent
+// kubeswitch processes from interleaving the read-modify-write of config.
+func withConfigLock(configAccess clientcmd.ConfigAccess, fn func() error) error {
+	path := getStatePath(configAccess, configLockFilename)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("Open lock file: %w", err)
+	}
+	defer file.Close()
+
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			return fmt.Errorf("Lock config: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errConfigLocked
+		}
+		time.Sleep(configLockRetry)
+	}
+	defer unlockFile(file)
+
+	return fn()
+}
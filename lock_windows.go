@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// Advisory lock is not supported on windows, always succeed.
+func tryLockFile(_ *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(_ *os.File) {}
@@ -5,14 +5,31 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit with code %d", e.code)
+}
+
 func Cmd(out io.Writer) *cobra.Command {
-	patchOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	patchOptions := newConfigAccess(pathOptions)
+
+	var profile string
+	var kubeconfig string
+
+	var check bool
+	var checkOutput bool
+	var checkTimeout time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "kubeswitch",
@@ -23,6 +40,31 @@ func Cmd(out io.Writer) *cobra.Command {
 		SilenceErrors: true,
 		SilenceUsage:  true,
 
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if kubeconfig != "" && profile != "" {
+				return errors.New("Cannot use --kubeconfig with --profile")
+			}
+			explicit, err := applyKubeconfig(pathOptions, kubeconfig)
+			if err != nil {
+				return err
+			}
+			if !explicit {
+				err = applyProfile(pathOptions, profile)
+				if err != nil {
+					return err
+				}
+			}
+			switch cmd.Name() {
+			case "doctor", "secure":
+				// They report the insecure files themselves.
+				return nil
+			case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+				return nil
+			}
+			// Print to stderr, do not break the scripts reading stdout.
+			return warnInsecureFiles(os.Stderr, patchOptions)
+		},
+
 		RunE: func(_ *cobra.Command, _ []string) error {
 			config, err := patchOptions.GetStartingConfig()
 			if err != nil {
@@ -30,6 +72,21 @@ func Cmd(out io.Writer) *cobra.Command {
 			}
 
 			ctxName := config.CurrentContext
+			if check {
+				status := statusUnreachable
+				if _, ok := config.Contexts[ctxName]; ok {
+					status = checkContext(config, ctxName, checkTimeout)
+				}
+				if checkOutput {
+					// The status is for scripts such as prompts, print to stdout.
+					fmt.Fprintln(os.Stdout, status)
+				}
+				if status != statusOk {
+					return &exitError{code: 1}
+				}
+				return nil
+			}
+
 			if ctxName == "" {
 				return errors.New("No context selected")
 			}
@@ -49,11 +106,49 @@ func Cmd(out io.Writer) *cobra.Command {
 		},
 	}
 
-	cmd.AddCommand(Set(out, patchOptions))
-	cmd.AddCommand(Use(out, patchOptions))
-	cmd.AddCommand(Ns(out, patchOptions))
-	cmd.AddCommand(Del(out, patchOptions))
+	flags := cmd.Flags()
+	flags.BoolVar(&check, "check", false, "Check if the current cluster is reachable, exit with non-zero code if not")
+	flags.BoolVarP(&checkOutput, "output", "o", false, "Print the check status (ok, unreachable or unauthorized)")
+	flags.DurationVar(&checkTimeout, "check-timeout", 2*time.Second, "The timeout for checking cluster")
+
+	infoOut := &quietWriter{out: out}
+	cmd.PersistentFlags().BoolVarP(&infoOut.quiet, "quiet", "q", false, "Suppress the informational messages")
+	cmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use, default is env KUBESWITCH_KUBECONFIG")
+	cmd.PersistentFlags().StringVar(&profile, "profile", "", "Use the kubeconfig of the profile defined in config, default is env KUBESWITCH_PROFILE")
+	cmd.PersistentFlags().DurationVar(&globalTimeout, "timeout", globalTimeout, "The timeout of the operations touching the network, 0 means no timeout")
+	cmd.PersistentFlags().StringVar(&sourceURL, "source-url", "", "The remote URL to fetch kubeconfig from, override the config remote.url")
+	cmd.PersistentFlags().BoolVar(&disableAudit, "no-audit", false, "Do not write the switch audit log, override the config disableAudit")
+	cmd.PersistentFlags().StringVar(&contextPrefix, "context-prefix", "", "Only show the clusters with the prefix, override the config contextPrefix")
+
+	cmd.AddCommand(Set(infoOut, patchOptions))
+	cmd.AddCommand(Use(infoOut, patchOptions))
+	cmd.AddCommand(Ns(infoOut, patchOptions))
+	cmd.AddCommand(Project(infoOut, patchOptions))
+	cmd.AddCommand(Del(infoOut, patchOptions))
+	cmd.AddCommand(Undelete(infoOut, patchOptions))
 	cmd.AddCommand(List(out, patchOptions))
+	cmd.AddCommand(Discover(infoOut, patchOptions))
+	cmd.AddCommand(GenContexts(infoOut, patchOptions))
+	cmd.AddCommand(Rename(infoOut, patchOptions))
+	cmd.AddCommand(Copy(infoOut, patchOptions))
+	cmd.AddCommand(Disable(infoOut, patchOptions))
+	cmd.AddCommand(Enable(infoOut, patchOptions))
+	cmd.AddCommand(Log(out, patchOptions))
+	cmd.AddCommand(Server(patchOptions))
+	cmd.AddCommand(Status(out, patchOptions))
+	cmd.AddCommand(Current(infoOut, patchOptions))
+	cmd.AddCommand(Ping(out, patchOptions))
+	cmd.AddCommand(Show(infoOut, patchOptions))
+	cmd.AddCommand(Export(infoOut, patchOptions))
+	cmd.AddCommand(Exec(infoOut, patchOptions))
+	cmd.AddCommand(Shell(infoOut, patchOptions))
+	cmd.AddCommand(Namespaces(infoOut, patchOptions))
+	cmd.AddCommand(ClearCache(infoOut, patchOptions))
+	cmd.AddCommand(ValidateConfig(infoOut, patchOptions))
+	cmd.AddCommand(Doctor(infoOut, patchOptions))
+	cmd.AddCommand(Secure(infoOut, patchOptions))
+	cmd.AddCommand(Backup(infoOut, patchOptions))
+	cmd.AddCommand(Restore(infoOut, patchOptions))
 
 	return cmd
 }
@@ -64,6 +159,10 @@ func main() {
 
 	err := cmd.Execute()
 	if err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		fmt.Fprintf(out, "%s: %v\n", color.RedString("error"), err)
 		os.Exit(1)
 	}
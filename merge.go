@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func mergeContext(dst, src *clientcmdapi.Config, srcName, name string) error {
+	ctx, ok := src.Contexts[srcName]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q", srcName)
+	}
+	cluster, ok := src.Clusters[ctx.Cluster]
+	if !ok {
+		return fmt.Errorf("Cannot find cluster %q for context %q", ctx.Cluster, srcName)
+	}
+	authInfo, ok := src.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return fmt.Errorf("Cannot find user %q for context %q", ctx.AuthInfo, srcName)
+	}
+
+	ns := ctx.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+
+	// Clear the origin, or the new entries loaded from another file (such as
+	// the temp file of discover) would be written back to it.
+	dst.Clusters[name] = cluster.DeepCopy()
+	dst.Clusters[name].LocationOfOrigin = ""
+	dst.AuthInfos[name] = authInfo.DeepCopy()
+	dst.AuthInfos[name].LocationOfOrigin = ""
+	dst.Contexts[name] = &clientcmdapi.Context{
+		Cluster:    name,
+		AuthInfo:   name,
+		Namespace:  ns,
+		Extensions: ctx.DeepCopy().Extensions,
+	}
+	return nil
+}
+
+// copyContextEntries copies the context, and its cluster and user if missing
+// in dst, from src.
+func copyContextEntries(dst, src *clientcmdapi.Config, name string) error {
+	ctx, ok := src.Contexts[name]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q", name)
+	}
+	if _, ok = dst.Clusters[ctx.Cluster]; !ok {
+		if cluster, ok := src.Clusters[ctx.Cluster]; ok {
+			dst.Clusters[ctx.Cluster] = cluster.DeepCopy()
+		}
+	}
+	if _, ok = dst.AuthInfos[ctx.AuthInfo]; !ok {
+		if authInfo, ok := src.AuthInfos[ctx.AuthInfo]; ok {
+			dst.AuthInfos[ctx.AuthInfo] = authInfo.DeepCopy()
+		}
+	}
+	dst.Contexts[name] = ctx.DeepCopy()
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// resolvedConfigAccess resolves the symlinks in kubeconfig paths, so that
+// ModifyConfig writes through the link to the target file. Otherwise the lock
+// file and the relativized paths would be based on the link location.
+type resolvedConfigAccess struct {
+	clientcmd.ConfigAccess
+}
+
+func (a *resolvedConfigAccess) GetLoadingPrecedence() []string {
+	paths := a.ConfigAccess.GetLoadingPrecedence()
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = resolveSymlink(path)
+	}
+	return resolved
+}
+
+func (a *resolvedConfigAccess) GetDefaultFilename() string {
+	return resolveSymlink(a.ConfigAccess.GetDefaultFilename())
+}
+
+func (a *resolvedConfigAccess) GetExplicitFile() string {
+	path := a.ConfigAccess.GetExplicitFile()
+	if path == "" {
+		return ""
+	}
+	return resolveSymlink(path)
+}
+
+func resolveSymlink(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
+
+func modifyConfig(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config) error {
+	if transformAccess, ok := configAccess.(*transformConfigAccess); ok {
+		path, err := transformAccess.getEncryptedPath()
+		if err != nil {
+			return err
+		}
+		if path != "" {
+			return transformAccess.writeEncrypted(path, config)
+		}
+	}
+	return clientcmd.ModifyConfig(&resolvedConfigAccess{ConfigAccess: configAccess}, *config, true)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const nsCacheFilename = ".ns_cache"
+
+type nsCacheItem struct {
+	Time       time.Time `yaml:"time"`
+	Namespaces []string  `yaml:"namespaces"`
+}
+
+type namespacesOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+	stdout       io.Writer
+
+	all    bool
+	output string
+
+	timeout  time.Duration
+	workers  int
+	cacheTTL time.Duration
+	noCache  bool
+}
+
+func Namespaces(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &namespacesOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "namespaces [--all] [-o json|yaml]",
+		Short: "Dump the namespaces of clusters from server",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			switch opts.output {
+			case "json", "yaml":
+			default:
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			return opts.run()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&opts.all, "all", "a", false, "Dump the namespaces of all clusters rather than the current one")
+	flags.StringVarP(&opts.output, "output", "o", "json", "The output format, one of json and yaml")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("json", "yaml"))
+	flags.DurationVar(&opts.timeout, "fetch-timeout", 5*time.Second, "The timeout for fetching namespaces from each cluster")
+	flags.IntVar(&opts.workers, "workers", 10, "The max number of clusters to fetch concurrently")
+	flags.DurationVar(&opts.cacheTTL, "cache-ttl", 5*time.Minute, "How long the fetched namespaces are cached")
+	flags.BoolVar(&opts.noCache, "no-cache", false, "Ignore the cache and always fetch from server")
+
+	return cmd
+}
+
+func (o *namespacesOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if o.all {
+		names, err = filterContextNames(o.configAccess, getContextNames(config))
+		if err != nil {
+			return err
+		}
+	} else {
+		if config.CurrentContext == "" {
+			return errors.New("No context selected")
+		}
+		names = []string{config.CurrentContext}
+	}
+	if len(names) == 0 {
+		return errors.New("No cluster to dump")
+	}
+
+	cache := make(map[string]*nsCacheItem)
+	err = readState(o.configAccess, nsCacheFilename, &cache)
+	if err != nil {
+		return err
+	}
+
+	results := make(map[string][]string, len(names))
+	var toFetch []string
+	now := time.Now()
+	for _, name := range names {
+		item, ok := cache[name]
+		if ok && !o.noCache && now.Sub(item.Time) < o.cacheTTL {
+			results[name] = item.Namespaces
+			continue
+		}
+		toFetch = append(toFetch, name)
+	}
+
+	if len(toFetch) > 0 {
+		fetched, errs := o.fetch(toFetch)
+		for _, name := range toFetch {
+			if err, ok := errs[name]; ok {
+				// Skip the unreachable cluster rather than failing the whole dump.
+				fmt.Fprintf(o.out, "%s: skip cluster %q: %v\n", color.YellowString("warning"), name, err)
+				continue
+			}
+			results[name] = fetched[name]
+			cache[name] = &nsCacheItem{Time: now, Namespaces: fetched[name]}
+		}
+		err = writeState(o.configAccess, nsCacheFilename, cache)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.output == "yaml" {
+		encoder := yaml.NewEncoder(o.stdout)
+		encoder.SetIndent(2)
+		return encoder.Encode(results)
+	}
+	encoder := json.NewEncoder(o.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+func (o *namespacesOptions) fetch(names []string) (map[string][]string, map[string]error) {
+	results := make(map[string][]string, len(names))
+	errs := make(map[string]error)
+
+	workers := o.workers
+	if workers <= 0 {
+		workers = 1
+	}
+	tasks := make(chan string)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range tasks {
+				items, err := o.fetchOne(name)
+
+				lock.Lock()
+				if err != nil {
+					errs[name] = err
+				} else {
+					results[name] = items
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range names {
+		tasks <- name
+	}
+	close(tasks)
+	wg.Wait()
+
+	return results, errs
+}
+
+func (o *namespacesOptions) fetchOne(name string) ([]string, error) {
+	client, err := newKubeClient(o.configAccess, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := newTimeoutContext(context.Background(), o.timeout)
+	defer cancel()
+
+	return listServerNamespaces(ctx, client, "")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	navStateFilename = ".switch_nav"
+
+	// The legacy state file only records the last cluster, it is read for
+	// migration when there is no navigation state.
+	legacyLastContextFilename = ".last_switch_cluster"
+)
+
+// navState is the single source of truth for where the user has been.
+//
+//   - History records the visited clusters, the most recent first. Every
+//     switch (including "use -", "--push" and "--pop") moves the target to
+//     the front, so the current cluster is normally the first one.
+//   - "use -" switches to the most recent cluster in history that is not the
+//     current one.
+//   - Stack records the clusters pushed by "use --push", "use --pop" switches
+//     back to the top one. Pop does not touch the history except for recording
+//     the switch itself.
+type navState struct {
+	History []string `yaml:"history,omitempty"`
+	Stack   []string `yaml:"stack,omitempty"`
+}
+
+func readNavState(configAccess clientcmd.ConfigAccess) (*navState, error) {
+	state := new(navState)
+	err := readState(configAccess, navStateFilename, state)
+	if err != nil {
+		return nil, err
+	}
+	if len(state.History) > 0 || len(state.Stack) > 0 {
+		return state, nil
+	}
+
+	path := getStatePath(configAccess, legacyLastContextFilename)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if last := string(data); last != "" {
+		state.History = []string{last}
+	}
+	return state, nil
+}
+
+func writeNavState(configAccess clientcmd.ConfigAccess, state *navState) error {
+	return writeState(configAccess, navStateFilename, state)
+}
+
+// switchTo records the switch from one cluster to another, the from cluster
+// is pushed to the stack with push (used by "use --push").
+func (s *navState) switchTo(from, to string, push bool) {
+	if push && from != to && from != "" {
+		s.push(from)
+	}
+	s.visit(from, to)
+}
+
+func (s *navState) visit(from, to string) {
+	if from != "" {
+		s.moveToFront(from)
+	}
+	s.moveToFront(to)
+}
+
+func (s *navState) moveToFront(name string) {
+	history := make([]string, 0, len(s.History)+1)
+	history = append(history, name)
+	for _, item := range s.History {
+		if item != name {
+			history = append(history, item)
+		}
+	}
+	if len(history) > maxHistory {
+		history = history[:maxHistory]
+	}
+	s.History = history
+}
+
+func (s *navState) last(current string) string {
+	for _, name := range s.History {
+		if name != current {
+			return name
+		}
+	}
+	return ""
+}
+
+func (s *navState) recent(current string) []string {
+	var names []string
+	for _, name := range s.History {
+		if name != current {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (s *navState) push(name string) {
+	s.Stack = append(s.Stack, name)
+}
+
+func (s *navState) pop() (string, bool) {
+	if len(s.Stack) == 0 {
+		return "", false
+	}
+	name := s.Stack[len(s.Stack)-1]
+	s.Stack = s.Stack[:len(s.Stack)-1]
+	return name, true
+}
+
+func (s *navState) rename(oldName, newName string) bool {
+	var changed bool
+	for _, names := range [][]string{s.History, s.Stack} {
+		for i, name := range names {
+			if name == oldName {
+				names[i] = newName
+				changed = true
+			}
+		}
+	}
+	return changed
+}
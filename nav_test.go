@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runNavOp applies an operation like "use NAME", "use -", "push NAME", "pop"
+// or "history NAME" (select NAME from the recent clusters) to the state.
+func runNavOp(state *navState, current, op string) (string, error) {
+	fields := strings.Fields(op)
+	switch fields[0] {
+	case "use":
+		name := fields[1]
+		if name == "-" {
+			name = state.last(current)
+			if name == "" {
+				return "", fmt.Errorf("no last cluster")
+			}
+		}
+		state.switchTo(current, name, false)
+		return name, nil
+
+	case "push":
+		state.switchTo(current, fields[1], true)
+		return fields[1], nil
+
+	case "pop":
+		name, ok := state.pop()
+		if !ok {
+			return "", fmt.Errorf("empty stack")
+		}
+		state.switchTo(current, name, false)
+		return name, nil
+
+	case "history":
+		if !slices.Contains(state.recent(current), fields[1]) {
+			return "", fmt.Errorf("%q is not in recent clusters", fields[1])
+		}
+		state.switchTo(current, fields[1], false)
+		return fields[1], nil
+	}
+	return "", fmt.Errorf("unknown op %q", op)
+}
+
+func TestNavState(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		ops     []string
+		wantErr string
+
+		wantCurrent string
+		wantHistory []string
+		wantStack   []string
+		wantRecent  []string
+	}{
+		{
+			name:        "use dash toggles",
+			current:     "a",
+			ops:         []string{"use b", "use -", "use -"},
+			wantCurrent: "b",
+			wantHistory: []string{"b", "a"},
+			wantRecent:  []string{"a"},
+		},
+		{
+			name:    "use dash without history",
+			current: "a",
+			ops:     []string{"use -"},
+			wantErr: "no last cluster",
+		},
+		{
+			name:        "push and pop",
+			current:     "a",
+			ops:         []string{"push b", "push c", "pop"},
+			wantCurrent: "b",
+			wantHistory: []string{"b", "c", "a"},
+			wantStack:   []string{"a"},
+			wantRecent:  []string{"c", "a"},
+		},
+		{
+			name:        "use dash between push and pop",
+			current:     "a",
+			ops:         []string{"push b", "push c", "use -", "pop"},
+			wantCurrent: "b",
+			wantHistory: []string{"b", "c", "a"},
+			wantStack:   []string{"a"},
+			wantRecent:  []string{"c", "a"},
+		},
+		{
+			name:        "pop then use dash",
+			current:     "a",
+			ops:         []string{"push b", "pop", "use -"},
+			wantCurrent: "b",
+			wantHistory: []string{"b", "a"},
+			wantRecent:  []string{"a"},
+		},
+		{
+			name:    "pop empty stack",
+			current: "a",
+			ops:     []string{"push b", "pop", "pop"},
+			wantErr: "empty stack",
+		},
+		{
+			name:        "push the current cluster",
+			current:     "a",
+			ops:         []string{"push a"},
+			wantCurrent: "a",
+			wantHistory: []string{"a"},
+		},
+		{
+			name:        "push without current cluster",
+			current:     "",
+			ops:         []string{"push a"},
+			wantCurrent: "a",
+			wantHistory: []string{"a"},
+		},
+		{
+			name:        "history moves to front",
+			current:     "a",
+			ops:         []string{"use b", "use c", "history a", "use -"},
+			wantCurrent: "c",
+			wantHistory: []string{"c", "a", "b"},
+			wantRecent:  []string{"a", "b"},
+		},
+		{
+			name:        "history keeps the stack",
+			current:     "a",
+			ops:         []string{"push b", "use c", "history a", "pop"},
+			wantCurrent: "a",
+			wantHistory: []string{"a", "c", "b"},
+			wantRecent:  []string{"c", "b"},
+		},
+		{
+			name:    "history excludes the current cluster",
+			current: "a",
+			ops:     []string{"use b", "history b"},
+			wantErr: `"b" is not in recent clusters`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := new(navState)
+			current := tt.current
+			var err error
+			for _, op := range tt.ops {
+				current, err = runNavOp(state, current, op)
+				if err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if current != tt.wantCurrent {
+				t.Errorf("got current %q, want %q", current, tt.wantCurrent)
+			}
+			if !slices.Equal(state.History, tt.wantHistory) {
+				t.Errorf("got history %v, want %v", state.History, tt.wantHistory)
+			}
+			if !slices.Equal(state.Stack, tt.wantStack) {
+				t.Errorf("got stack %v, want %v", state.Stack, tt.wantStack)
+			}
+			if recent := state.recent(current); !slices.Equal(recent, tt.wantRecent) {
+				t.Errorf("got recent %v, want %v", recent, tt.wantRecent)
+			}
+		})
+	}
+}
+
+func TestNavStateMaxHistory(t *testing.T) {
+	state := new(navState)
+	current := ""
+	for i := 0; i < maxHistory+10; i++ {
+		name := fmt.Sprintf("c%d", i)
+		state.switchTo(current, name, false)
+		current = name
+	}
+	if len(state.History) != maxHistory {
+		t.Fatalf("got %d history items, want %d", len(state.History), maxHistory)
+	}
+	if state.History[0] != current {
+		t.Errorf("got first history %q, want %q", state.History[0], current)
+	}
+}
+
+func TestNavStateRename(t *testing.T) {
+	state := &navState{
+		History: []string{"a", "b", "c"},
+		Stack:   []string{"b", "a"},
+	}
+	if !state.rename("b", "d") {
+		t.Fatal("rename should report changed")
+	}
+	if want := []string{"a", "d", "c"}; !slices.Equal(state.History, want) {
+		t.Errorf("got history %v, want %v", state.History, want)
+	}
+	if want := []string{"d", "a"}; !slices.Equal(state.Stack, want) {
+		t.Errorf("got stack %v, want %v", state.Stack, want)
+	}
+	if state.rename("x", "y") {
+		t.Error("rename of unknown cluster should not report changed")
+	}
+}
@@ -6,30 +6,67 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+const (
+	nsStackFilename   = ".ns_stack"
+	nsAppliedFilename = ".ns_applied"
+	nsHistoryFilename = ".ns_history"
+	nsLastFilename    = ".last_switch_ns"
+)
+
 type nsOptions struct {
 	configAccess clientcmd.ConfigAccess
 	out          io.Writer
+	stdout       io.Writer
+
+	ns     string
+	output string
+
+	push bool
+	pop  bool
+
+	group string
+
+	contextFile string
+	dryRun      bool
+
+	create bool
+
+	alpha  bool
+	sortBy string
+
+	showSource bool
+
+	selector string
 
-	ns string
+	accessible bool
+
+	print bool
+}
+
+// newNsOptions returns the options with the same defaults as the ns flags,
+// for the commands switching namespace after switching cluster.
+func newNsOptions(configAccess clientcmd.ConfigAccess, out io.Writer) *nsOptions {
+	return &nsOptions{configAccess: configAccess, out: out, stdout: os.Stdout, sortBy: nsSortRecent}
 }
 
 func Ns(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
-	opts := &nsOptions{configAccess: configAccess, out: out}
+	opts := newNsOptions(configAccess, out)
 
 	cmd := &cobra.Command{
-		Use:   "ns [NAME]",
+		Use:   "ns [NAME|@INDEX]",
 		Short: "Switch to a namespace",
 
 		Args: cobra.MaximumNArgs(1),
@@ -40,159 +77,857 @@ func Ns(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
 			if len(args) >= 1 {
 				opts.ns = args[0]
 			}
+			if opts.alpha {
+				opts.sortBy = nsSortName
+			}
+			if opts.sortBy != nsSortName && opts.sortBy != nsSortRecent {
+				return fmt.Errorf("Unsupported sort %q", opts.sortBy)
+			}
+			if opts.pop && (opts.push || opts.ns != "") {
+				return errors.New("The --pop flag cannot be used with --push or namespace name")
+			}
+			if opts.output != "" && opts.output != "name" {
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			if opts.create && (opts.ns == "-" || opts.pop) {
+				return errors.New("The --create flag cannot be used with --pop or \"-\"")
+			}
+			if opts.print {
+				if opts.push || opts.pop || opts.create || opts.group != "" || opts.contextFile != "" {
+					return errors.New("The --print flag cannot be used with --push, --pop, --create, --group or --context-file")
+				}
+				return opts.runPrint()
+			}
+			if opts.group != "" && opts.contextFile != "" {
+				return errors.New("The --group flag cannot be used with --context-file")
+			}
+			if opts.group != "" || opts.contextFile != "" {
+				return withConfigLock(configAccess, opts.runBatch)
+			}
+			if opts.dryRun {
+				return errors.New("The --dry-run flag can only be used with --group or --context-file")
+			}
 			return opts.run()
 		},
 	}
 
+	cmd.AddCommand(NsList(out, configAccess))
+	cmd.AddCommand(NsAlias(out, configAccess))
+	cmd.AddCommand(NsBookmark(out, configAccess))
+
+	flags := cmd.Flags()
+	flags.BoolVar(&opts.push, "push", false, "Push the current namespace to stack before switching")
+	flags.BoolVar(&opts.pop, "pop", false, "Pop a namespace from stack and switch to it")
+	flags.StringVarP(&opts.group, "group", "g", "", "Switch namespace for all the clusters in the group")
+	flags.StringVar(&opts.contextFile, "context-file", "", "Switch namespace for the clusters listed in the file, one name per line")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Only print the changes when using --group or --context-file")
+	flags.BoolVar(&opts.alpha, "alpha", false, "Sort the namespaces alphabetically in fzf rather than by recently used")
+	_ = flags.MarkDeprecated("alpha", "use --sort=name instead")
+	flags.StringVar(&opts.sortBy, "sort", nsSortRecent, "How to sort the namespaces in fzf, one of name and recent")
+	cmd.PersistentFlags().StringVar(&nsAliasMergeStrategy, "merge-strategy", "", "How to combine the alias entries matching the cluster, one of first, union and longest (default), override the config nsAliasMerge")
+	flags.StringVarP(&opts.selector, "selector", "l", "", "Only select the namespaces matching the label selector from server, such as env=prod")
+	flags.BoolVar(&opts.accessible, "accessible", false, "Only select the namespaces in which you can list pods, checked by SelfSubjectAccessReview")
+	flags.BoolVar(&opts.showSource, "show-source", false, "Show the source of namespaces (alias or server) in fzf")
+	flags.BoolVarP(&opts.create, "create", "c", false, "Create the namespace if it does not exist, or add an entry to create new namespace in fzf")
+	flags.StringVarP(&opts.output, "output", "o", "", "Print the selected namespace to stdout, only support \"name\"")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("name"))
+	flags.BoolVar(&opts.print, "print", false, "Only print the resolved namespace to stdout without switching")
+
 	return cmd
 }
 
-func (o *nsOptions) run() error {
+// runPrint resolves the namespace like run, but does not change anything, it
+// helps scripts and debugging alias rules.
+func (o *nsOptions) runPrint() error {
 	config, err := o.configAccess.GetStartingConfig()
 	if err != nil {
 		return err
 	}
+	if _, ok := config.Contexts[config.CurrentContext]; !ok {
+		return fmt.Errorf("Cannot find context %q", config.CurrentContext)
+	}
 
 	ns, err := o.selectNs(config.CurrentContext)
 	if err != nil {
 		return err
 	}
+	fmt.Fprintln(o.stdout, ns)
+	return nil
+}
+
+// run selects the namespace without lock, since fzf and fetching namespaces
+// may take a long time, then switches to it with lock.
+func (o *nsOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name := config.CurrentContext
+	if _, ok := config.Contexts[name]; !ok {
+		return fmt.Errorf("Cannot find context %q", name)
+	}
+
+	// The last namespace of "-" is resolved with lock, after saving the one
+	// changed outside kubeswitch.
+	var ns string
+	if !o.pop && o.ns != "-" {
+		ns, err = o.selectNs(name)
+		if err != nil {
+			return err
+		}
+	}
 
+	return withConfigLock(o.configAccess, func() error {
+		return o.apply(name, ns)
+	})
+}
+
+// apply switches the namespace of the cluster, the config is reloaded in case
+// it was modified during selection. For --pop and "-", the ns is resolved here.
+func (o *nsOptions) apply(name, ns string) error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if config.CurrentContext != name {
+		return fmt.Errorf("The current cluster was switched to %q by another process, please try again", config.CurrentContext)
+	}
 	ctx, ok := config.Contexts[config.CurrentContext]
 	if !ok {
 		return fmt.Errorf("Cannot find context %q", config.CurrentContext)
 	}
+
+	applied := make(map[string]string)
+	err = readState(o.configAccess, nsAppliedFilename, &applied)
+	if err != nil {
+		return err
+	}
+	if appliedNs, ok := applied[config.CurrentContext]; ok && appliedNs != ctx.Namespace {
+		// The namespace was changed outside kubeswitch (such as kubectl), the
+		// one we applied becomes the last namespace.
+		err = o.saveLast(config.CurrentContext, appliedNs)
+		if err != nil {
+			return fmt.Errorf("Save last ns: %w", err)
+		}
+	}
+
+	stack := make(stackState)
+	if o.push || o.pop {
+		err = readState(o.configAccess, nsStackFilename, &stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.pop {
+		var ok bool
+		ns, ok = stack.pop(config.CurrentContext)
+		if !ok {
+			return errors.New("The namespace stack is empty")
+		}
+	} else if o.ns == "-" {
+		ns, err = o.selectNs(config.CurrentContext)
+		if err != nil {
+			return err
+		}
+	}
+
 	lastNs := ctx.Namespace
 	changed := lastNs != ns
 	ctx.Namespace = ns
 
-	err = clientcmd.ModifyConfig(o.configAccess, *config, true)
+	err = modifyConfig(o.configAccess, config)
 	if err != nil {
 		return fmt.Errorf("Update config: %w", err)
 	}
+	if o.push && changed {
+		stack.push(config.CurrentContext, lastNs)
+	}
+	if o.pop || (o.push && changed) {
+		err = writeState(o.configAccess, nsStackFilename, stack)
+		if err != nil {
+			return err
+		}
+	}
 	if changed {
-		err = o.saveLast(lastNs)
+		err = o.saveLast(config.CurrentContext, lastNs)
 		if err != nil {
 			return fmt.Errorf("Save last ns: %w", err)
 		}
 	}
+	if applied[config.CurrentContext] != ns {
+		applied[config.CurrentContext] = ns
+		err = writeState(o.configAccess, nsAppliedFilename, applied)
+		if err != nil {
+			return err
+		}
+	}
+	err = o.saveHistory(config.CurrentContext, ns)
+	if err != nil {
+		return fmt.Errorf("Save namespace history: %w", err)
+	}
+	recordAuditLog(o.out, o.configAccess, "ns", config.CurrentContext, lastNs, ns)
 
 	fmt.Fprintf(o.out, "Switch to namespace %s\n", nameColor().Sprint(ns))
+	if o.output == "name" {
+		fmt.Fprintln(o.stdout, ns)
+	}
 	return nil
 }
 
+func (o *nsOptions) runBatch() error {
+	flag := "--group"
+	if o.contextFile != "" {
+		flag = "--context-file"
+	}
+	if o.ns == "" || o.ns == "-" {
+		return fmt.Errorf("The namespace name is required when using %s", flag)
+	}
+	if o.push || o.pop {
+		return fmt.Errorf("The %s flag cannot be used with --push or --pop", flag)
+	}
+
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	var source string
+	if o.contextFile != "" {
+		names, err = readContextFile(o.contextFile, config)
+		if err != nil {
+			return err
+		}
+		source = fmt.Sprintf("context file %q", o.contextFile)
+	} else {
+		names, err = o.getGroupNames()
+		if err != nil {
+			return err
+		}
+		source = fmt.Sprintf("group %q", o.group)
+	}
+
+	lastNs := make(map[string]string, len(names))
+	for _, name := range names {
+		ctx, ok := config.Contexts[name]
+		if !ok {
+			return fmt.Errorf("Cannot find context %q in %s", name, source)
+		}
+		lastNs[name] = ctx.Namespace
+		ctx.Namespace = o.ns
+	}
+
+	if o.dryRun {
+		for _, name := range names {
+			fmt.Fprintf(o.out, "Would switch cluster %q from namespace %q to %q\n", name, lastNs[name], o.ns)
+		}
+		return nil
+	}
+
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Update config: %w", err)
+	}
+
+	applied := make(map[string]string)
+	err = readState(o.configAccess, nsAppliedFilename, &applied)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		err = o.saveHistory(name, o.ns)
+		if err != nil {
+			return fmt.Errorf("Save namespace history: %w", err)
+		}
+		applied[name] = o.ns
+		recordAuditLog(o.out, o.configAccess, "ns", name, lastNs[name], o.ns)
+	}
+	err = writeState(o.configAccess, nsAppliedFilename, applied)
+	if err != nil {
+		return err
+	}
+
+	if o.group != "" {
+		fmt.Fprintf(o.out, "Switch to namespace %s for %d clusters in group %s\n", nameColor().Sprint(o.ns), len(names), nameColor().Sprint(o.group))
+	} else {
+		fmt.Fprintf(o.out, "Switch to namespace %s for %d clusters\n", nameColor().Sprint(o.ns), len(names))
+	}
+	return nil
+}
+
+func (o *nsOptions) getGroupNames() ([]string, error) {
+	cfg, err := readConfig(o.configAccess)
+	if err != nil {
+		return nil, err
+	}
+	names, ok := cfg.Groups[o.group]
+	if !ok {
+		return nil, fmt.Errorf("Cannot find group %q", o.group)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No cluster in group %q", o.group)
+	}
+	return names, nil
+}
+
 func (o *nsOptions) selectNs(name string) (string, error) {
 	if o.ns != "" {
 		ns := o.ns
+		if strings.HasPrefix(ns, "@") {
+			return resolveBookmark(o.configAccess, name, ns)
+		}
 		if ns == "-" {
 			var err error
-			ns, err = o.readLast()
+			ns, err = o.readLast(name)
 			if err != nil {
 				return "", fmt.Errorf("Read last ns: %w", err)
 			}
 			if ns == "" {
 				return "", errors.New("You have not switch to any namespace yet")
 			}
+			return ns, nil
 		}
-		return ns, nil
+		if o.create {
+			return o.createNs(name, ns)
+		}
+
+		items, _, err := o.listNamespaces(name)
+		if err != nil {
+			// Cannot resolve namespaces, trust the user input.
+			return ns, nil
+		}
+		match, err := matchName(items, ns)
+		if err != nil {
+			return "", err
+		}
+		if match == "" || match == ns || isFullNamePrefix(match, ns) {
+			return ns, nil
+		}
+		fmt.Fprintf(o.out, "Namespace %q not found, match %s\n", ns, nameColor().Sprint(match))
+		return match, nil
 	}
-	alias, err := o.readAlias()
+
+	items, sources, err := o.listNamespaces(name)
 	if err != nil {
 		return "", err
 	}
 
-	var items []string
-	for prefix, nsList := range alias {
-		if strings.HasPrefix(name, prefix) {
-			items = nsList
-			break
+	if len(items) == 0 && !o.create {
+		return "", errors.New("No namespace to use")
+	}
+	if len(items) == 1 && !o.create {
+		fmt.Fprintf(o.out, "Only one namespace %s, select it\n", nameColor().Sprint(items[0]))
+		return items[0], nil
+	}
+
+	items, err = sortNamespaces(o.configAccess, name, items, sources, o.sortBy)
+	if err != nil {
+		return "", err
+	}
+
+	display := make([][]string, 0, len(items)+1)
+	if o.create {
+		display = append(display, []string{nsCreateItem})
+	}
+	for _, item := range items {
+		row := []string{item}
+		if o.showSource {
+			row = append(row, fmt.Sprintf("[%s]", sources[item]))
 		}
+		display = append(display, row)
+	}
+
+	idx, err := searchInteractiveColumns(o.configAccess, display)
+	if err != nil {
+		return "", err
+	}
+	if o.create {
+		if idx == 0 {
+			ns, err := prompt("Namespace name to create:")
+			if err != nil {
+				return "", err
+			}
+			if ns == "" {
+				return "", errors.New("Empty namespace name")
+			}
+			return o.createNs(name, ns)
+		}
+		idx--
+	}
+
+	return items[idx], nil
+}
+
+// listNamespaces fetches the namespaces matching the label selector from
+// server directly, the alias is ignored since it has no labels.
+func (o *nsOptions) listNamespaces(name string) ([]string, map[string]string, error) {
+	var items []string
+	var sources map[string]string
+	var err error
+	if o.selector == "" {
+		items, sources, err = listNamespaces(o.configAccess, name)
+	} else {
+		items, err = getServerNamespacesBySelector(o.configAccess, name, o.selector)
+		sources = getNsSources(items, nsSourceServer)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(items) == 0 {
-		filename := o.configAccess.GetDefaultFilename()
-		var restConfig *rest.Config
-		restConfig, err = clientcmd.BuildConfigFromFlags("", filename)
+
+	if o.accessible && len(items) > 0 {
+		items, err = o.filterAccessible(name, items)
 		if err != nil {
-			return "", err
+			return nil, nil, err
+		}
+	}
+	return items, sources, nil
+}
+
+func (o *nsOptions) filterAccessible(name string, items []string) ([]string, error) {
+	client, err := newKubeClient(o.configAccess, name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+	accessible, err := filterAccessibleNamespaces(ctx, client, items, 10)
+	if err != nil {
+		// The server may not support or allow the review, show all the
+		// namespaces rather than nothing.
+		fmt.Fprintf(o.out, "%s: cannot check the accessible namespaces, show all: %v\n", color.YellowString("warning"), err)
+		return items, nil
+	}
+	return accessible, nil
+}
+
+func (o *nsOptions) saveHistory(name, ns string) error {
+	history := make(historyState)
+	err := readState(o.configAccess, nsHistoryFilename, &history)
+	if err != nil {
+		return err
+	}
+	history.visit(name, ns)
+	return writeState(o.configAccess, nsHistoryFilename, history)
+}
+
+func (o *nsOptions) createNs(name, ns string) (string, error) {
+	client, err := newKubeClient(o.configAccess, name)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+	items, err := listServerNamespaces(ctx, client, "")
+	if err != nil {
+		return "", err
+	}
+	for _, item := range items {
+		if item == ns {
+			return ns, nil
 		}
+	}
 
-		var client *kubernetes.Clientset
-		client, err = kubernetes.NewForConfig(restConfig)
+	if similar := findSimilarName(items, ns); similar != "" {
+		ok, err := confirm(fmt.Sprintf("Did you mean %q? Create %q anyway?", similar, ns))
 		if err != nil {
-			return "", fmt.Errorf("Init kube client: %w", err)
+			return "", err
 		}
+		if !ok {
+			return "", errors.New("Cancelled creating namespace")
+		}
+	}
 
-		ctx := context.Background()
-		var nsList *v1.NamespaceList
-		nsList, err = client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	err = createNamespace(client, ns)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(o.out, "Create namespace %s\n", nameColor().Sprint(ns))
+	return ns, nil
+}
+
+const nsCreateItem = "<create new namespace>"
+
+const (
+	nsSortName   = "name"
+	nsSortRecent = "recent"
+)
+
+// sortNamespaces sorts the namespaces by name or recently used. The server
+// namespaces are always sorted by name first, while the alias keeps the
+// order defined by user unless sorting by name.
+func sortNamespaces(configAccess clientcmd.ConfigAccess, name string, items []string, sources map[string]string, sortBy string) ([]string, error) {
+	fromServer := !slices.ContainsFunc(items, func(item string) bool {
+		return sources[item] != nsSourceServer
+	})
+	if fromServer || sortBy == nsSortName {
+		items = slices.Clone(items)
+		sort.Strings(items)
+	}
+	if sortBy != nsSortRecent {
+		return items, nil
+	}
+
+	history := make(historyState)
+	err := readState(configAccess, nsHistoryFilename, &history)
+	if err != nil {
+		return nil, err
+	}
+	return sortByRecent(items, history[name]), nil
+}
+
+const (
+	nsSourceAlias  = "alias"
+	nsSourceServer = "server"
+)
+
+// listNamespaces returns the namespaces of the cluster and where each of them
+// comes from. The alias entries matched by pattern come from server.
+func listNamespaces(configAccess clientcmd.ConfigAccess, name string) ([]string, map[string]string, error) {
+	nsList, err := matchNsAlias(configAccess, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nsList) > 0 {
+		return resolveNsAlias(configAccess, name, nsList)
+	}
+
+	items, err := getServerNamespaces(configAccess, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, getNsSources(items, nsSourceServer), nil
+}
+
+func getNsSources(items []string, source string) map[string]string {
+	sources := make(map[string]string, len(items))
+	for _, item := range items {
+		sources[item] = source
+	}
+	return sources
+}
+
+const (
+	nsAliasMergeFirst   = "first"
+	nsAliasMergeUnion   = "union"
+	nsAliasMergeLongest = "longest"
+)
+
+// nsAliasMergeStrategy is set by the flag "--merge-strategy" of ns, override
+// the config nsAliasMerge.
+var nsAliasMergeStrategy string
+
+// matchNsAlias combines the alias entries whose key matches the context,
+// according to the merge strategy. For the "longest" strategy, the longest key
+// wins, and the first one in file wins if they have the same length.
+func matchNsAlias(configAccess clientcmd.ConfigAccess, name string) ([]string, error) {
+	strategy := nsAliasMergeStrategy
+	if strategy == "" {
+		cfg, err := readConfig(configAccess)
 		if err != nil {
-			return "", fmt.Errorf("Get namespaces from server: %w", err)
+			return nil, err
 		}
-		items = make([]string, len(nsList.Items))
-		for i, ns := range nsList.Items {
-			items[i] = ns.Name
+		strategy = cfg.NsAliasMerge
+	}
+	if strategy == "" {
+		strategy = nsAliasMergeLongest
+	}
+
+	entries, err := readNsAliasEntries(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*nsAliasEntry
+	for _, entry := range entries {
+		if entry.match(name) && len(entry.nsList) > 0 {
+			matched = append(matched, entry)
 		}
 	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
 
-	if len(items) == 0 {
-		return "", errors.New("No namespace to use")
+	switch strategy {
+	case nsAliasMergeFirst:
+		return matched[0].nsList, nil
+
+	case nsAliasMergeUnion:
+		var nsList []string
+		for _, entry := range matched {
+			for _, ns := range entry.nsList {
+				if !slices.Contains(nsList, ns) {
+					nsList = append(nsList, ns)
+				}
+			}
+		}
+		return nsList, nil
+
+	case nsAliasMergeLongest:
+		longest := matched[0]
+		for _, entry := range matched[1:] {
+			if len(entry.prefix) > len(longest.prefix) {
+				longest = entry
+			}
+		}
+		return longest.nsList, nil
+
+	default:
+		return nil, fmt.Errorf("Invalid alias merge strategy %q, should be first, union or longest", strategy)
 	}
+}
+
+func getServerNamespaces(configAccess clientcmd.ConfigAccess, name string) ([]string, error) {
+	return getServerNamespacesBySelector(configAccess, name, "")
+}
 
-	idx, err := searchFzf(items)
+func getServerNamespacesBySelector(configAccess clientcmd.ConfigAccess, name, selector string) ([]string, error) {
+	client, err := newKubeClient(configAccess, name)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	ctx, cancel := newTimeoutContext(context.Background(), 0)
+	defer cancel()
+	return listServerNamespaces(ctx, client, selector)
+}
 
-	return items[idx], nil
+// resolveNsAlias expands the alias entries. Besides the plain namespace, an
+// entry can be "re:PATTERN" to include the server namespaces matching the
+// regex, "!re:PATTERN" to exclude the matched ones, or "-NAME" to exclude a
+// namespace. Excludes are always applied after includes.
+func resolveNsAlias(configAccess clientcmd.ConfigAccess, name string, nsList []string) ([]string, map[string]string, error) {
+	var serverItems []string
+	getServerItems := func() ([]string, error) {
+		if serverItems != nil {
+			return serverItems, nil
+		}
+		var err error
+		serverItems, err = getServerNamespaces(configAccess, name)
+		return serverItems, err
+	}
+
+	var excludeNames []string
+	var excludePatterns []*regexp.Regexp
+	var items []string
+	sources := make(map[string]string)
+	include := func(ns, source string) {
+		if _, ok := sources[ns]; ok {
+			return
+		}
+		sources[ns] = source
+		items = append(items, ns)
+	}
+
+	for _, entry := range nsList {
+		switch {
+		case strings.HasPrefix(entry, "!re:"):
+			re, err := regexp.Compile(strings.TrimPrefix(entry, "!re:"))
+			if err != nil {
+				return nil, nil, fmt.Errorf("Invalid alias pattern %q: %w", entry, err)
+			}
+			excludePatterns = append(excludePatterns, re)
+
+		case strings.HasPrefix(entry, "-"):
+			excludeNames = append(excludeNames, strings.TrimPrefix(entry, "-"))
+
+		case strings.HasPrefix(entry, "re:"):
+			re, err := regexp.Compile(strings.TrimPrefix(entry, "re:"))
+			if err != nil {
+				return nil, nil, fmt.Errorf("Invalid alias pattern %q: %w", entry, err)
+			}
+			all, err := getServerItems()
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, ns := range all {
+				if re.MatchString(ns) {
+					include(ns, nsSourceServer)
+				}
+			}
+
+		default:
+			include(entry, nsSourceAlias)
+		}
+	}
+
+	if len(excludeNames) == 0 && len(excludePatterns) == 0 {
+		return items, sources, nil
+	}
+	filtered := make([]string, 0, len(items))
+	for _, ns := range items {
+		if slices.Contains(excludeNames, ns) {
+			continue
+		}
+		excluded := false
+		for _, re := range excludePatterns {
+			if re.MatchString(ns) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, ns)
+		}
+	}
+	return filtered, sources, nil
 }
 
-func (o *nsOptions) readAlias() (map[string][]string, error) {
-	return readNsAlias(o.configAccess)
+// isFullNamePrefix reports whether the name looks like a full name typed by
+// the user rather than an abbreviation of match, that is, match continues it
+// with another "-" or "." separated word, such as "app" for "app-v2".
+func isFullNamePrefix(match, name string) bool {
+	rest, ok := strings.CutPrefix(match, name)
+	if !ok || rest == "" {
+		return false
+	}
+	return rest[0] == '-' || rest[0] == '.'
 }
 
-func readNsAlias(configAccess clientcmd.ConfigAccess) (map[string][]string, error) {
+func getNsAliasPath(configAccess clientcmd.ConfigAccess) (string, error) {
+	var cfg *Config
+	if os.Getenv("KUBESWITCH_NS_ALIAS") == "" {
+		var err error
+		cfg, err = readConfig(configAccess)
+		if err != nil {
+			return "", err
+		}
+	}
+	return resolveNsAliasPath(configAccess, cfg)
+}
+
+// resolveNsAliasPath returns the alias path with the given config rather
+// than the one on disk, the cfg can be nil.
+func resolveNsAliasPath(configAccess clientcmd.ConfigAccess, cfg *Config) (string, error) {
 	filename := configAccess.GetDefaultFilename()
 	dir := filepath.Dir(filename)
-	aliasPath := filepath.Join(dir, "ns_alias.yaml")
+
+	path := os.Getenv("KUBESWITCH_NS_ALIAS")
+	if path == "" && cfg != nil {
+		path = cfg.NsAlias
+	}
+	if path == "" {
+		return filepath.Join(dir, "ns_alias.yaml"), nil
+	}
+
+	return expandPath(path, dir)
+}
+
+type nsAliasEntry struct {
+	prefix string
+	nsList []string
+
+	match func(name string) bool
+}
+
+// parseNsAliasKey returns the matcher of the alias key. The key wrapped in
+// slashes is a regex, such as "/^prod-(us|eu)$/"; the key containing "*", "?"
+// or "[" is a glob pattern, such as "prod-*"; otherwise it is a prefix.
+func parseNsAliasKey(key string) (func(string) bool, error) {
+	if len(key) >= 2 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/") {
+		re, err := regexp.Compile(key[1 : len(key)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex alias key %q: %w", key, err)
+		}
+		return re.MatchString, nil
+	}
+	if strings.ContainsAny(key, "*?[") {
+		_, err := path.Match(key, "")
+		if err != nil {
+			return nil, fmt.Errorf("Invalid glob alias key %q: %w", key, err)
+		}
+		return func(name string) bool {
+			ok, _ := path.Match(key, name)
+			return ok
+		}, nil
+	}
+	return func(name string) bool {
+		return strings.HasPrefix(name, key)
+	}, nil
+}
+
+// readNsAliasEntries reads the alias entries in the file order, which is
+// required by the "first" merge strategy.
+func readNsAliasEntries(configAccess clientcmd.ConfigAccess) ([]*nsAliasEntry, error) {
+	aliasPath, err := getNsAliasPath(configAccess)
+	if err != nil {
+		return nil, err
+	}
 
 	file, err := os.Open(aliasPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return make(map[string][]string), nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("Open alias file: %w", err)
 	}
 	defer file.Close()
 
-	decoder := yaml.NewDecoder(file)
-	alias := make(map[string][]string, 0)
-	err = decoder.Decode(&alias)
+	var doc yaml.Node
+	err = yaml.NewDecoder(file).Decode(&doc)
 	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("Decode alias file: %w", err)
 	}
 
-	return alias, nil
+	// Validate the whole document first to get the readable type errors.
+	alias := make(map[string][]string)
+	err = doc.Decode(&alias)
+	if err != nil {
+		return nil, fmt.Errorf("Decode alias file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	content := doc.Content[0].Content
+	entries := make([]*nsAliasEntry, 0, len(content)/2)
+	for i := 0; i+1 < len(content); i += 2 {
+		prefix := content[i].Value
+		match, err := parseNsAliasKey(prefix)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &nsAliasEntry{prefix: prefix, nsList: alias[prefix], match: match})
+	}
+	return entries, nil
 }
 
-func (o *nsOptions) saveLast(name string) error {
-	path := o.getLastPath()
-	return os.WriteFile(path, []byte(name), 0644)
+func (o *nsOptions) saveLast(name, ns string) error {
+	last, _, err := readLastNsState(o.configAccess)
+	if err != nil {
+		return err
+	}
+	last[name] = ns
+	return writeState(o.configAccess, nsLastFilename, last)
 }
 
-func (o *nsOptions) readLast() (string, error) {
-	path := o.getLastPath()
-	data, err := os.ReadFile(path)
+func (o *nsOptions) readLast(name string) (string, error) {
+	last, legacy, err := readLastNsState(o.configAccess)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
-		}
 		return "", err
 	}
-	return string(data), nil
+	if ns, ok := last[name]; ok {
+		return ns, nil
+	}
+	return legacy, nil
 }
 
-func (o *nsOptions) getLastPath() string {
-	filename := o.configAccess.GetDefaultFilename()
-	dir := filepath.Dir(filename)
-	return filepath.Join(dir, ".last_switch_ns")
+// readLastNsState reads the last namespace of each context. The file used to
+// be a plain namespace shared by all contexts, it is returned as legacy.
+func readLastNsState(configAccess clientcmd.ConfigAccess) (map[string]string, string, error) {
+	data, err := os.ReadFile(getStatePath(configAccess, nsLastFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]string), "", nil
+		}
+		return nil, "", err
+	}
+
+	last := make(map[string]string)
+	err = yaml.Unmarshal(data, &last)
+	if err != nil {
+		return make(map[string]string), strings.TrimSpace(string(data)), nil
+	}
+	return last, "", nil
 }
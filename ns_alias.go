@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type nsAliasEditOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	editor  string
+	current bool
+}
+
+func NsAlias(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage the namespace alias",
+	}
+
+	cmd.AddCommand(NsAliasEdit(out, configAccess))
+
+	return cmd
+}
+
+func NsAliasEdit(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &nsAliasEditOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "edit [--current]",
+		Short: "Edit the namespace alias file",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if opts.current {
+				return opts.runCurrent()
+			}
+			return opts.run()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.editor, "editor", "e", "", "The editor command to edit alias, override the config file and env VISUAL/EDITOR")
+	flags.BoolVar(&opts.current, "current", false, "Only edit the alias entry of the current cluster")
+
+	return cmd
+}
+
+func (o *nsAliasEditOptions) run() error {
+	path, err := getNsAliasPath(o.configAccess)
+	if err != nil {
+		return err
+	}
+
+	ok, err := o.edit(path)
+	if err != nil || !ok {
+		return err
+	}
+
+	// Validate the edited file, so the mistake can be found immediately.
+	_, err = readNsAliasEntries(o.configAccess)
+	return err
+}
+
+func (o *nsAliasEditOptions) runCurrent() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if config.CurrentContext == "" {
+		return errors.New("No context selected")
+	}
+
+	path, err := getNsAliasPath(o.configAccess)
+	if err != nil {
+		return err
+	}
+	entries, err := readNsAliasEntries(o.configAccess)
+	if err != nil {
+		return err
+	}
+	alias := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		alias[entry.prefix] = entry.nsList
+	}
+
+	// Edit the first longest key matching the current cluster, or add a new
+	// entry for it.
+	key := config.CurrentContext
+	var matched bool
+	for _, entry := range entries {
+		if entry.match(config.CurrentContext) && (!matched || len(entry.prefix) > len(key)) {
+			key = entry.prefix
+			matched = true
+		}
+	}
+
+	data, err := yaml.Marshal(alias[key])
+	if err != nil {
+		return fmt.Errorf("Encode alias: %w", err)
+	}
+	if len(alias[key]) == 0 {
+		data = nil
+	}
+
+	file, err := os.CreateTemp("", "edit-ns-alias-*.yaml")
+	if err != nil {
+		return fmt.Errorf("Create temp file: %w", err)
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Write temp file: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("Close temp file: %w", err)
+	}
+
+	fmt.Fprintf(o.out, "Edit the namespace alias %s\n", nameColor().Sprint(key))
+	ok, err := o.edit(tmpPath)
+	if err != nil || !ok {
+		return err
+	}
+
+	data, err = os.ReadFile(tmpPath)
+	if err != nil {
+		return fmt.Errorf("Read temp file after editing: %w", err)
+	}
+	var nsList []string
+	err = yaml.Unmarshal(data, &nsList)
+	if err != nil {
+		return fmt.Errorf("Decode edited alias, should be a list of namespaces: %w", err)
+	}
+
+	err = setNsAliasEntry(path, key, nsList)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.out, "Update namespace alias %s done\n", nameColor().Sprint(key))
+	return nil
+}
+
+// setNsAliasEntry updates the entry of the alias file in place, keeping the
+// order of entries and the comments, since the order decides which entry wins
+// with the "first" merge strategy. An empty list removes the entry.
+func setNsAliasEntry(path, key string, nsList []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Read alias file: %w", err)
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return fmt.Errorf("Decode alias file: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return errors.New("Invalid alias file, expect a mapping")
+	}
+
+	if len(nsList) == 0 {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == key {
+				root.Content = append(root.Content[:i], root.Content[i+2:]...)
+				break
+			}
+		}
+	} else {
+		var listNode yaml.Node
+		err = listNode.Encode(nsList)
+		if err != nil {
+			return fmt.Errorf("Encode alias: %w", err)
+		}
+		value := getOrAddMappingValue(root, key, yaml.SequenceNode)
+		value.Content = listNode.Content
+	}
+
+	data, err = yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("Encode alias file: %w", err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("Write alias file: %w", err)
+	}
+	return nil
+}
+
+func (o *nsAliasEditOptions) edit(path string) (bool, error) {
+	editorArgs, err := getEditor(o.configAccess, o.editor)
+	if err != nil {
+		return false, err
+	}
+	editor := editorArgs[0]
+
+	err = runEditor(editorArgs, path)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return false, fmt.Errorf("Cannot find editor %q, please check your editor config", editor)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			fmt.Fprintln(o.out, "Cancelled")
+			return false, nil
+		}
+		return false, fmt.Errorf("Use editor %q to edit alias failed: %w", editor, err)
+	}
+	return true, nil
+}
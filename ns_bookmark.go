@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The bookmarked namespaces of each context, referenced by "@INDEX" (1-based).
+const nsBookmarksFilename = ".ns_bookmarks"
+
+type bookmarkState map[string][]string
+
+func readBookmarks(configAccess clientcmd.ConfigAccess) (bookmarkState, error) {
+	bookmarks := make(bookmarkState)
+	err := readState(configAccess, nsBookmarksFilename, &bookmarks)
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+// resolveBookmark returns the namespace of "@INDEX" reference.
+func resolveBookmark(configAccess clientcmd.ConfigAccess, name, ref string) (string, error) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(ref, "@"))
+	if err != nil {
+		return "", fmt.Errorf("Invalid bookmark %q, should be @INDEX", ref)
+	}
+	bookmarks, err := readBookmarks(configAccess)
+	if err != nil {
+		return "", err
+	}
+	items := bookmarks[name]
+	if idx < 1 || idx > len(items) {
+		return "", fmt.Errorf("Cannot find bookmark %s, there are %d bookmarks", ref, len(items))
+	}
+	return items[idx-1], nil
+}
+
+func formatBookmarks(items []string) string {
+	refs := make([]string, len(items))
+	for i, item := range items {
+		refs[i] = fmt.Sprintf("@%d %s", i+1, item)
+	}
+	return strings.Join(refs, ", ")
+}
+
+type nsBookmarkOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	ns string
+}
+
+func NsBookmark(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bookmark",
+		Short: "Manage the namespace bookmarks of the current cluster, switch to them by \"ns @INDEX\"",
+	}
+
+	cmd.AddCommand(nsBookmarkCommand(out, configAccess, "add [NAME]", "Bookmark the namespace, default is the current one", cobra.MaximumNArgs(1), (*nsBookmarkOptions).add))
+	cmd.AddCommand(nsBookmarkCommand(out, configAccess, "del NAME|@INDEX", "Delete the bookmark", cobra.ExactArgs(1), (*nsBookmarkOptions).del))
+	cmd.AddCommand(nsBookmarkCommand(out, configAccess, "list", "List the bookmarks", cobra.NoArgs, (*nsBookmarkOptions).list))
+
+	return cmd
+}
+
+// nsBookmarkCommand builds the bookmark subcommand, run modifies the bookmarks
+// of the current cluster and reports whether they should be saved.
+func nsBookmarkCommand(out io.Writer, configAccess clientcmd.ConfigAccess, use, short string, args cobra.PositionalArgs, run func(*nsBookmarkOptions, string, bookmarkState) (bool, error)) *cobra.Command {
+	opts := &nsBookmarkOptions{configAccess: configAccess, out: out}
+
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+
+		Args: args,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) >= 1 {
+				opts.ns = args[0]
+			}
+			config, err := configAccess.GetStartingConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := config.Contexts[config.CurrentContext]; !ok {
+				return fmt.Errorf("Cannot find context %q", config.CurrentContext)
+			}
+			if opts.ns == "" {
+				opts.ns = config.Contexts[config.CurrentContext].Namespace
+				if opts.ns == "" {
+					opts.ns = "default"
+				}
+			}
+
+			bookmarks, err := readBookmarks(configAccess)
+			if err != nil {
+				return err
+			}
+			changed, err := run(opts, config.CurrentContext, bookmarks)
+			if err != nil || !changed {
+				return err
+			}
+			return writeState(configAccess, nsBookmarksFilename, bookmarks)
+		},
+	}
+}
+
+func (o *nsBookmarkOptions) add(name string, bookmarks bookmarkState) (bool, error) {
+	if slices.Contains(bookmarks[name], o.ns) {
+		return false, fmt.Errorf("The namespace %q is already bookmarked", o.ns)
+	}
+	bookmarks[name] = append(bookmarks[name], o.ns)
+	fmt.Fprintf(o.out, "Bookmark namespace %s as @%d\n", nameColor().Sprint(o.ns), len(bookmarks[name]))
+	return true, nil
+}
+
+func (o *nsBookmarkOptions) del(name string, bookmarks bookmarkState) (bool, error) {
+	ns := o.ns
+	if strings.HasPrefix(ns, "@") {
+		var err error
+		ns, err = resolveBookmark(o.configAccess, name, ns)
+		if err != nil {
+			return false, err
+		}
+	}
+	idx := slices.Index(bookmarks[name], ns)
+	if idx < 0 {
+		return false, fmt.Errorf("The namespace %q is not bookmarked", ns)
+	}
+	bookmarks[name] = slices.Delete(bookmarks[name], idx, idx+1)
+	if len(bookmarks[name]) == 0 {
+		delete(bookmarks, name)
+	}
+	fmt.Fprintf(o.out, "Delete bookmark %s\n", nameColor().Sprint(ns))
+	return true, nil
+}
+
+func (o *nsBookmarkOptions) list(name string, bookmarks bookmarkState) (bool, error) {
+	items := bookmarks[name]
+	if len(items) == 0 {
+		return false, errors.New("No bookmark in current cluster")
+	}
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{fmt.Sprintf("@%d", i+1), item}
+	}
+	ShowTable(o.out, []string{"index", "namespace"}, rows)
+	return false, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type nsListOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+	stdout       io.Writer
+
+	output string
+	sortBy string
+}
+
+type nsListItem struct {
+	Name    string `json:"name" yaml:"name"`
+	Source  string `json:"source" yaml:"source"`
+	Current bool   `json:"current" yaml:"current"`
+}
+
+func NsList(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &nsListOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List namespaces of the current cluster",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			switch opts.output {
+			case "table", "json", "yaml":
+			default:
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			if opts.sortBy != nsSortName && opts.sortBy != nsSortRecent {
+				return fmt.Errorf("Unsupported sort %q", opts.sortBy)
+			}
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", "The output format, one of table, json and yaml")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("table", "json", "yaml"))
+	cmd.Flags().StringVar(&opts.sortBy, "sort", nsSortName, "How to sort the namespaces, one of name and recent")
+
+	return cmd
+}
+
+func (o *nsListOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	ctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q", config.CurrentContext)
+	}
+
+	names, sources, err := listNamespaces(o.configAccess, config.CurrentContext)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.New("No namespace to show")
+	}
+	names, err = sortNamespaces(o.configAccess, config.CurrentContext, names, sources, o.sortBy)
+	if err != nil {
+		return err
+	}
+
+	items := make([]*nsListItem, len(names))
+	for i, name := range names {
+		items[i] = &nsListItem{
+			Name:    name,
+			Source:  sources[name],
+			Current: name == ctx.Namespace,
+		}
+	}
+
+	switch o.output {
+	case "json":
+		encoder := json.NewEncoder(o.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+
+	case "yaml":
+		encoder := yaml.NewEncoder(o.stdout)
+		encoder.SetIndent(2)
+		return encoder.Encode(items)
+	}
+
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		var cur string
+		if item.Current {
+			cur = "*"
+		}
+		rows[i] = []string{cur, item.Name, item.Source}
+	}
+	ShowTable(o.out, []string{"", "name", "source"}, rows)
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type pingOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	names []string
+
+	timeout  time.Duration
+	workers  int
+	deadline time.Duration
+
+	watch    bool
+	interval time.Duration
+}
+
+func Ping(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &pingOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "ping [--watch] [NAME...]",
+		Short: "Check the clusters and refresh the health cache used by list",
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts.names = args
+			if opts.interval <= 0 {
+				return errors.New("The --interval should be positive")
+			}
+			return opts.run()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.DurationVar(&opts.timeout, "check-timeout", 2*time.Second, "The timeout for checking each cluster")
+	flags.IntVar(&opts.workers, "workers", 10, "The max number of clusters to check concurrently")
+	flags.DurationVar(&opts.deadline, "deadline", 30*time.Second, "The deadline for checking all clusters")
+	flags.BoolVarP(&opts.watch, "watch", "w", false, "Check the clusters repeatedly and redraw the table until interrupted")
+	flags.DurationVar(&opts.interval, "interval", 10*time.Second, "The interval between checks when using --watch")
+
+	return cmd
+}
+
+func (o *pingOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	names := o.names
+	if len(names) == 0 {
+		names, err = filterContextNames(o.configAccess, getContextNames(config))
+		if err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("No cluster to ping")
+	}
+	for _, name := range names {
+		if _, ok := config.Contexts[name]; !ok {
+			return fmt.Errorf("Cannot find cluster %q", name)
+		}
+	}
+
+	if !o.watch {
+		return o.ping(context.Background(), config, names, o.out)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	for {
+		// Render into a buffer first, so that the screen is not left blank
+		// while checking.
+		var buf bytes.Buffer
+		err = o.ping(ctx, config, names, &buf)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Move the cursor to top-left and clear the screen before redrawing.
+		fmt.Fprint(o.out, "\033[H\033[2J")
+		fmt.Fprintf(o.out, "Every %s, updated at %s\n\n", o.interval, time.Now().Format(time.TimeOnly))
+		_, err = buf.WriteTo(o.out)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *pingOptions) ping(parent context.Context, config *clientcmdapi.Config, names []string, out io.Writer) error {
+	ctx, cancel := newTimeoutContext(parent, o.deadline)
+	defer cancel()
+	status := checkContexts(ctx, config, names, o.workers, o.timeout, nil)
+	if parent.Err() != nil {
+		// Interrupted, the results are incomplete.
+		return nil
+	}
+
+	err := saveHealthCache(o.configAccess, status)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(names))
+	for i, name := range names {
+		s, ok := status[name]
+		if !ok {
+			s = statusUnchecked
+		}
+		rows[i] = []string{name, s}
+	}
+	ShowTable(out, []string{"name", "status"}, rows)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// contextPrefix is set by the global flag "--context-prefix", to only show
+// the contexts with the prefix.
+var contextPrefix string
+
+func getContextPrefix(configAccess clientcmd.ConfigAccess) (string, error) {
+	if contextPrefix != "" {
+		return contextPrefix, nil
+	}
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return "", err
+	}
+	return cfg.ContextPrefix, nil
+}
+
+func filterContextNames(configAccess clientcmd.ConfigAccess, names []string) ([]string, error) {
+	prefix, err := getContextPrefix(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		return names, nil
+	}
+
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// productionLabel is the context extension marking a production cluster, set
+// by "kubeswitch set NAME --extension prod=true".
+const productionLabel = "prod"
+
+// isProductionContext reports whether switching to the context requires
+// confirmation, by the "prod" label or the config productionContexts.
+func isProductionContext(cfg *Config, ctx *clientcmdapi.Context, name string) (bool, error) {
+	if value, ok := getContextExtensions(ctx)[productionLabel]; ok {
+		prod, err := strconv.ParseBool(value)
+		if err != nil || prod {
+			// Any value other than false marks production, to be safe.
+			return true, nil
+		}
+	}
+	for _, pattern := range cfg.ProductionContexts {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("Invalid productionContexts pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
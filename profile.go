@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// applyProfile points the path options to the kubeconfig of the profile, the
+// profiles are defined in the config next to the default kubeconfig. Each
+// profile has its own state files next to its kubeconfig.
+func applyProfile(pathOptions *clientcmd.PathOptions, profile string) error {
+	if profile == "" {
+		profile = os.Getenv("KUBESWITCH_PROFILE")
+	}
+	if profile == "" {
+		return nil
+	}
+
+	configAccess := newConfigAccess(pathOptions)
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return err
+	}
+	path, ok := cfg.Profiles[profile]
+	if !ok {
+		return fmt.Errorf("Cannot find profile %q in config", profile)
+	}
+	path, err = expandPath(path, filepath.Dir(configAccess.GetDefaultFilename()))
+	if err != nil {
+		return err
+	}
+
+	pathOptions.LoadingRules.ExplicitPath = path
+	return nil
+}
+
+// applyKubeconfig points the path options to the explicit kubeconfig, default
+// is env KUBESWITCH_KUBECONFIG. Returns false if no kubeconfig specified.
+func applyKubeconfig(pathOptions *clientcmd.PathOptions, path string) (bool, error) {
+	if path == "" {
+		path = os.Getenv("KUBESWITCH_KUBECONFIG")
+	}
+	if path == "" {
+		return false, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, fmt.Errorf("Get work dir: %w", err)
+	}
+	path, err = expandPath(path, wd)
+	if err != nil {
+		return false, err
+	}
+
+	pathOptions.LoadingRules.ExplicitPath = path
+	return true, nil
+}
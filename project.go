@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func Project(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "project",
+		Short: "Manage the projects, each is a set of cluster and namespace pairs",
+	}
+
+	cmd.AddCommand(ProjectUse(out, configAccess))
+	cmd.AddCommand(ProjectList(out, configAccess))
+	cmd.AddCommand(ProjectAdd(out, configAccess))
+
+	return cmd
+}
+
+func completeProjectFunc(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := readConfig(getCompletionConfigAccess(cmd))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return getSortedKeys(cfg.Projects), cobra.ShellCompDirectiveNoFileComp
+}
+
+func ProjectUse(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "use NAME",
+		Short: "Select a cluster and namespace of the project and switch to them",
+
+		Args: cobra.ExactArgs(1),
+
+		ValidArgsFunction: completeProjectFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			cfg, err := readConfig(configAccess)
+			if err != nil {
+				return err
+			}
+			entries, ok := cfg.Projects[args[0]]
+			if !ok {
+				return fmt.Errorf("Cannot find project %q", args[0])
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("No entry in project %q", args[0])
+			}
+
+			entry := entries[0]
+			if len(entries) > 1 {
+				rows := make([][]string, len(entries))
+				for i, e := range entries {
+					rows[i] = []string{e.Context, e.Namespace}
+				}
+				idx, err := searchInteractiveColumns(configAccess, rows)
+				if err != nil {
+					return fmt.Errorf("Search fzf: %w", err)
+				}
+				entry = entries[idx]
+			}
+
+			useOpts := &useOptions{configAccess: configAccess, out: out, stdout: os.Stdout, name: entry.Context, fromFd: -1}
+			err = useOpts.run()
+			if err != nil {
+				return err
+			}
+			if entry.Namespace == "" {
+				return nil
+			}
+			nsOpts := newNsOptions(configAccess, out)
+			nsOpts.ns = entry.Namespace
+			return nsOpts.run()
+		},
+	}
+}
+
+func ProjectList(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the projects",
+
+		Args: cobra.NoArgs,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := readConfig(configAccess)
+			if err != nil {
+				return err
+			}
+			if len(cfg.Projects) == 0 {
+				return errors.New("No project in config file")
+			}
+
+			var rows [][]string
+			for _, name := range getSortedKeys(cfg.Projects) {
+				for _, entry := range cfg.Projects[name] {
+					rows = append(rows, []string{name, entry.Context, entry.Namespace})
+				}
+			}
+			ShowTable(out, []string{"project", "cluster", "namespace"}, rows)
+			return nil
+		},
+	}
+}
+
+func ProjectAdd(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "add NAME",
+		Short: "Add the current cluster and namespace to the project",
+
+		Args: cobra.ExactArgs(1),
+
+		ValidArgsFunction: completeProjectFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			config, err := configAccess.GetStartingConfig()
+			if err != nil {
+				return err
+			}
+			ctx, ok := config.Contexts[config.CurrentContext]
+			if !ok {
+				return fmt.Errorf("Cannot find context %q", config.CurrentContext)
+			}
+			entry := ProjectEntry{Context: config.CurrentContext, Namespace: ctx.Namespace}
+			if entry.Namespace == "" {
+				entry.Namespace = "default"
+			}
+
+			cfg, err := readConfig(configAccess)
+			if err != nil {
+				return err
+			}
+			for _, e := range cfg.Projects[args[0]] {
+				if e == entry {
+					return fmt.Errorf("The %s is already in project %q", entry, args[0])
+				}
+			}
+
+			err = addProjectEntry(getConfigPath(configAccess), args[0], entry)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Add %s to project %s\n", entry, nameColor().Sprint(args[0]))
+			return nil
+		},
+	}
+}
+
+func (e ProjectEntry) String() string {
+	return fmt.Sprintf("%s/%s", e.Context, e.Namespace)
+}
+
+// addProjectEntry appends the entry to the project in config file, it edits
+// the yaml node so that the other content and comments are kept.
+func addProjectEntry(path, name string, entry ProjectEntry) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return fmt.Errorf("Decode config file: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return errors.New("Invalid config file, expect a mapping")
+	}
+
+	projects := getOrAddMappingValue(root, "projects", yaml.MappingNode)
+	project := getOrAddMappingValue(projects, name, yaml.SequenceNode)
+
+	var entryNode yaml.Node
+	err = entryNode.Encode(entry)
+	if err != nil {
+		return fmt.Errorf("Encode project entry: %w", err)
+	}
+	project.Content = append(project.Content, &entryNode)
+
+	data, err = yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("Encode config file: %w", err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("Write config file: %w", err)
+	}
+	return nil
+}
+
+func getOrAddMappingValue(node *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			if value.Kind != kind {
+				// Such as "projects:" with null value.
+				value.Kind = kind
+				value.Tag = ""
+				value.Value = ""
+			}
+			return value
+		}
+	}
+	value := &yaml.Node{Kind: kind}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		value,
+	)
+	return value
+}
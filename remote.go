@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	remoteCacheFilename = ".remote_config"
+
+	defaultRemoteTTL     = time.Hour
+	defaultRemoteTimeout = 10 * time.Second
+)
+
+// sourceURL is set by the global flag "--source-url", override the config
+// "remote.url".
+var sourceURL string
+
+type remoteCache struct {
+	URL  string    `yaml:"url"`
+	Time time.Time `yaml:"time"`
+	Data string    `yaml:"data"`
+}
+
+// loadRemoteConfig fetches the kubeconfig distributed by the remote service,
+// returns nil if no remote is configured. The result is cached with a TTL.
+func loadRemoteConfig(configAccess clientcmd.ConfigAccess) (*clientcmdapi.Config, error) {
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	remote := cfg.Remote
+	if remote == nil {
+		remote = new(RemoteConfig)
+	}
+	url := remote.URL
+	if sourceURL != "" {
+		url = sourceURL
+	}
+	if url == "" {
+		return nil, nil
+	}
+	ttl := remote.TTL
+	if ttl <= 0 {
+		ttl = defaultRemoteTTL
+	}
+
+	var cache remoteCache
+	err = readState(configAccess, remoteCacheFilename, &cache)
+	if err != nil {
+		return nil, err
+	}
+	if cache.URL != url || time.Since(cache.Time) >= ttl {
+		data, err := fetchRemoteConfig(url, remote.Headers)
+		switch {
+		case err == nil:
+			cache = remoteCache{URL: url, Time: time.Now(), Data: string(data)}
+			// The remote config may contain credentials.
+			err = writeSecretState(configAccess, remoteCacheFilename, &cache)
+			if err != nil {
+				return nil, err
+			}
+
+		case cache.URL == url && cache.Data != "":
+			// Print to stderr, do not break the scripts reading stdout.
+			fmt.Fprintf(os.Stderr, "%s: %v, use the remote config cached %s ago\n", color.YellowString("warning"),
+				err, duration.HumanDuration(time.Since(cache.Time)))
+
+		default:
+			return nil, err
+		}
+	}
+
+	config, err := clientcmd.Load([]byte(cache.Data))
+	if err != nil {
+		return nil, fmt.Errorf("Load remote config: %w", err)
+	}
+	return config, nil
+}
+
+func fetchRemoteConfig(url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Build remote request: %w", err)
+	}
+	for key, value := range headers {
+		// Allow to reference token in env, such as "Bearer ${TOKEN}".
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+
+	timeout := defaultRemoteTimeout
+	if globalTimeout > 0 && globalTimeout < timeout {
+		timeout = globalTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Fetch remote config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetch remote config: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Read remote config: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("Empty remote config")
+	}
+	return data, nil
+}
+
+// withRemoteContexts returns a copy of config with the remote contexts that
+// do not exist locally, and the names of them.
+func withRemoteContexts(config, remote *clientcmdapi.Config) (*clientcmdapi.Config, map[string]struct{}, error) {
+	view := config.DeepCopy()
+	names := make(map[string]struct{})
+	for name := range remote.Contexts {
+		if _, ok := view.Contexts[name]; ok {
+			continue
+		}
+		err := mergeContext(view, remote, name, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid remote config: %w", err)
+		}
+		names[name] = struct{}{}
+	}
+	return view, names, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type renameOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	oldName string
+	newName string
+
+	force bool
+}
+
+func Rename(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &renameOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "rename OLD NEW",
+		Short: "Rename a cluster",
+
+		Args: cobra.ExactArgs(2),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts.oldName = args[0]
+			opts.newName = args[1]
+			return withConfigLock(configAccess, opts.run)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Overwrite the cluster if the new name already exists")
+
+	return cmd
+}
+
+func (o *renameOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, ok := config.Contexts[o.oldName]
+	if !ok {
+		return fmt.Errorf("Cannot find cluster %q", o.oldName)
+	}
+	if o.oldName == o.newName {
+		return errors.New("The new name is the same as the old one")
+	}
+	overwritten, ok := config.Contexts[o.newName]
+	if ok {
+		if !o.force {
+			return fmt.Errorf("Cluster %q already exists, please use --force to overwrite it", o.newName)
+		}
+		fmt.Fprintf(o.out, "%s: overwrite cluster %q\n", color.YellowString("warning"), o.newName)
+	}
+
+	delete(config.Contexts, o.oldName)
+	config.Contexts[o.newName] = ctx
+
+	// Remove the cluster and user of the overwritten context if no one else
+	// uses them, so that the renamed ones can take their names.
+	if overwritten != nil {
+		if countClusterRefs(config, overwritten.Cluster) == 0 {
+			delete(config.Clusters, overwritten.Cluster)
+		}
+		if countAuthInfoRefs(config, overwritten.AuthInfo) == 0 {
+			delete(config.AuthInfos, overwritten.AuthInfo)
+		}
+	}
+
+	// kubeswitch names the cluster and user after the context, rename them
+	// as well unless they are shared with other contexts, or the new name is
+	// already taken (it may be used by other contexts, even with --force).
+	_, clusterTaken := config.Clusters[o.newName]
+	if !clusterTaken && countClusterRefs(config, ctx.Cluster) <= 1 {
+		if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+			delete(config.Clusters, ctx.Cluster)
+			config.Clusters[o.newName] = cluster
+			ctx.Cluster = o.newName
+		}
+	}
+	_, authInfoTaken := config.AuthInfos[o.newName]
+	if !authInfoTaken && countAuthInfoRefs(config, ctx.AuthInfo) <= 1 {
+		if authInfo, ok := config.AuthInfos[ctx.AuthInfo]; ok {
+			delete(config.AuthInfos, ctx.AuthInfo)
+			config.AuthInfos[o.newName] = authInfo
+			ctx.AuthInfo = o.newName
+		}
+	}
+	if config.CurrentContext == o.oldName {
+		config.CurrentContext = o.newName
+	}
+
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Modify config: %w", err)
+	}
+
+	err = renameContextState(o.configAccess, o.oldName, o.newName)
+	if err != nil {
+		return fmt.Errorf("Rename state: %w", err)
+	}
+
+	fmt.Fprintf(o.out, "Rename cluster %q to %s\n", o.oldName, nameColor().Sprint(o.newName))
+	return nil
+}
+
+// countClusterRefs returns the number of contexts referencing the cluster,
+// including the renaming one (whose cluster may still be the key).
+func countClusterRefs(config *clientcmdapi.Config, key string) int {
+	var count int
+	for _, ctx := range config.Contexts {
+		if ctx.Cluster == key {
+			count++
+		}
+	}
+	return count
+}
+
+func countAuthInfoRefs(config *clientcmdapi.Config, key string) int {
+	var count int
+	for _, ctx := range config.Contexts {
+		if ctx.AuthInfo == key {
+			count++
+		}
+	}
+	return count
+}
+
+// renameContextState rewrites the references to the old context name in the
+// state files kubeswitch maintains, so that they will not dangle.
+func renameContextState(configAccess clientcmd.ConfigAccess, oldName, newName string) error {
+	nav, err := readNavState(configAccess)
+	if err != nil {
+		return err
+	}
+	if nav.rename(oldName, newName) {
+		err = writeNavState(configAccess, nav)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = renameStateKey[[]string](configAccess, nsStackFilename, oldName, newName)
+	if err != nil {
+		return err
+	}
+	err = renameStateKey[string](configAccess, nsAppliedFilename, oldName, newName)
+	if err != nil {
+		return err
+	}
+	err = renameStateKey[[]string](configAccess, nsHistoryFilename, oldName, newName)
+	if err != nil {
+		return err
+	}
+	// The legacy last namespace is shared by all contexts, nothing to rename.
+	last, legacy, err := readLastNsState(configAccess)
+	if err != nil {
+		return err
+	}
+	if ns, ok := last[oldName]; ok && legacy == "" {
+		delete(last, oldName)
+		last[newName] = ns
+		err = writeState(configAccess, nsLastFilename, last)
+		if err != nil {
+			return err
+		}
+	}
+	err = renameStateKey[[]string](configAccess, nsBookmarksFilename, oldName, newName)
+	if err != nil {
+		return err
+	}
+	err = renameDisabledContext(configAccess, oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The state files containing credentials, they should only be accessed by the
+// owner like kube config.
+var secretStateFilenames = []string{
+	remoteCacheFilename,
+	trashDirname,
+}
+
+// The other files managed by kubeswitch, they can be readable by others but
+// should not be writable.
+var publicStateFilenames = []string{
+	navStateFilename,
+	legacyLastContextFilename,
+	nsLastFilename,
+	nsStackFilename,
+	nsAppliedFilename,
+	nsHistoryFilename,
+	nsBookmarksFilename,
+	nsCacheFilename,
+	disabledContextsFilename,
+	healthCacheFilename,
+	auditLogFilename,
+	configLockFilename,
+}
+
+type insecureFile struct {
+	path string
+	mode fs.FileMode
+	want fs.FileMode
+}
+
+// findInsecureFiles returns the kube config and kubeswitch files whose
+// permissions are too loose. The kube config and secret files should be 0600
+// (0700 for directory), the others should not be writable by others. The
+// config file is secret when it has remote headers, which may contain tokens.
+func findInsecureFiles(configAccess clientcmd.ConfigAccess) ([]*insecureFile, error) {
+	var files []*insecureFile
+	check := func(path string, secret bool) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("Stat file: %w", err)
+		}
+		mode := info.Mode().Perm()
+		mask, want := fs.FileMode(0022), mode&^0022
+		if secret {
+			mask, want = 0077, 0600
+			if info.IsDir() {
+				want = 0700
+			}
+		}
+		if mode&mask != 0 {
+			files = append(files, &insecureFile{path: path, mode: mode, want: want})
+		}
+		return nil
+	}
+
+	for _, path := range configAccess.GetLoadingPrecedence() {
+		err := check(path, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range secretStateFilenames {
+		path := getStatePath(configAccess, name)
+		err := check(path, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	trashFiles, _ := filepath.Glob(filepath.Join(getStatePath(configAccess, trashDirname), "*"))
+	for _, path := range trashFiles {
+		err := check(path, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range publicStateFilenames {
+		err := check(getStatePath(configAccess, name), false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	err = check(getConfigPath(configAccess), cfg.Remote != nil && len(cfg.Remote.Headers) > 0)
+	if err != nil {
+		return nil, err
+	}
+	aliasPath, err := getNsAliasPath(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	err = check(aliasPath, false)
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (f *insecureFile) String() string {
+	return fmt.Sprintf("%s: file %s has insecure permissions %04o, should be %04o", color.YellowString("warning"), f.path, f.mode, f.want)
+}
+
+func secureFiles(out io.Writer, files []*insecureFile) error {
+	for _, file := range files {
+		err := os.Chmod(file.path, file.want)
+		if err != nil {
+			return fmt.Errorf("Change permissions of %s: %w", file.path, err)
+		}
+		fmt.Fprintf(out, "Change permissions of %s to %04o\n", file.path, file.want)
+	}
+	return nil
+}
+
+// warnInsecureFiles is called before each command when the config
+// "warnInsecure" is enabled.
+func warnInsecureFiles(out io.Writer, configAccess clientcmd.ConfigAccess) error {
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return err
+	}
+	if !cfg.WarnInsecure {
+		return nil
+	}
+	files, err := findInsecureFiles(configAccess)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Fprintln(out, file)
+	}
+	if len(files) > 0 {
+		fmt.Fprintln(out, "Run \"kubeswitch secure\" to fix them")
+	}
+	return nil
+}
+
+func Secure(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "secure",
+		Short: "Tighten the permissions of kube config and kubeswitch files",
+
+		Args: cobra.NoArgs,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			files, err := findInsecureFiles(configAccess)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				fmt.Fprintln(out, "All files are secure")
+				return nil
+			}
+			return secureFiles(out, files)
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type serverOptions struct {
+	configAccess clientcmd.ConfigAccess
+	stdout       io.Writer
+}
+
+func Server(configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &serverOptions{configAccess: configAccess, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "server",
+		Short: "Print the server URL of the current cluster",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return opts.run()
+		},
+	}
+
+	return cmd
+}
+
+func (o *serverOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	ctxName := config.CurrentContext
+	if ctxName == "" {
+		return errors.New("No context selected")
+	}
+	ctx, ok := config.Contexts[ctxName]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q", ctxName)
+	}
+	cluster, ok := config.Clusters[ctx.Cluster]
+	if !ok {
+		return fmt.Errorf("Cannot find cluster %q for context %q", ctx.Cluster, ctxName)
+	}
+
+	fmt.Fprintln(o.stdout, cluster.Server)
+	return nil
+}
@@ -5,11 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -18,15 +24,31 @@ type setOptions struct {
 	configAccess clientcmd.ConfigAccess
 	out          io.Writer
 
-	name     string
-	filename string
+	name      string
+	filename  string
+	editor    string
+	namespace string
+
+	tlsServerName    string
+	tlsServerNameSet bool
+
+	proxyURL    string
+	proxyURLSet bool
+
+	noEditorCancel bool
+
+	withReference bool
+
+	extensions []string
+
+	validate bool
 }
 
 func Set(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
 	opts := &setOptions{configAccess: configAccess, out: out}
 
 	cmd := &cobra.Command{
-		Use:   "set [-f filename] NAME",
+		Use:   "set [-f filename] [-n namespace] [--tls-server-name name] [--proxy-url url] [--extension key=value] NAME",
 		Short: "Set cluster",
 
 		Args: cobra.ExactArgs(1),
@@ -38,63 +60,144 @@ func Set(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
 			if opts.name == "" {
 				return cmd.Usage()
 			}
+			opts.tlsServerNameSet = cmd.Flags().Changed("tls-server-name")
+			opts.proxyURLSet = cmd.Flags().Changed("proxy-url")
+			if opts.proxyURL != "" {
+				proxyURL, err := url.Parse(opts.proxyURL)
+				if err != nil {
+					return fmt.Errorf("Invalid proxy URL %q: %w", opts.proxyURL, err)
+				}
+				switch proxyURL.Scheme {
+				case "http", "https", "socks5":
+				default:
+					return fmt.Errorf("Invalid proxy URL %q, the scheme should be http, https or socks5", opts.proxyURL)
+				}
+			}
+			for _, ext := range opts.extensions {
+				if !strings.Contains(ext, "=") {
+					return fmt.Errorf("Invalid extension %q, should be key=value", ext)
+				}
+			}
 			return opts.run()
 		},
 	}
 
 	flags := cmd.Flags()
 	flags.StringVarP(&opts.filename, "file", "f", "", "The merge config filename, if not provided, will open an editor to edit config")
+	flags.StringVarP(&opts.editor, "editor", "e", "", "The editor command to edit config, override the config file and env VISUAL/EDITOR")
+	flags.BoolVar(&opts.validate, "validate", false, "Validate the server URL and check if the cluster is reachable before writing")
+	flags.BoolVar(&opts.withReference, "with-reference", false, "Append the full redacted kube config as comments to the editing content for reference")
+	flags.BoolVar(&opts.noEditorCancel, "no-editor-cancel", false, "Treat the editor non-zero exit as an error rather than cancel")
+	flags.StringVar(&opts.tlsServerName, "tls-server-name", "", "Update the TLS server name of an existing cluster without editing, empty to unset")
+	flags.StringVar(&opts.proxyURL, "proxy-url", "", "Update the proxy URL of an existing cluster without editing, empty to unset")
+	flags.StringArrayVar(&opts.extensions, "extension", nil, "Set the kubeswitch extension key=value of the context without editing, empty value to remove")
+	flags.StringVarP(&opts.namespace, "namespace", "n", "", "The namespace of the context, if not provided, keep the current one or use config defaultNamespace (\"default\") for new cluster")
 
 	return cmd
 }
 
 func (o *setOptions) run() error {
+	if o.tlsServerNameSet || o.proxyURLSet || len(o.extensions) > 0 {
+		return withConfigLock(o.configAccess, o.updateFields)
+	}
+
 	config, err := o.configAccess.GetStartingConfig()
 	if err != nil {
 		return err
 	}
 
 	configEdit := o.getConfigToEdit(config)
-	newConfig, err := o.edit(configEdit)
+	var reference []byte
+	if o.withReference {
+		reference, err = getEditReference(config)
+		if err != nil {
+			return err
+		}
+	}
+	newConfig, err := o.edit(configEdit, reference)
 	if err != nil {
 		return err
 	}
+	if newConfig == nil {
+		fmt.Fprintln(o.out, "Cancelled")
+		return nil
+	}
 
 	if len(newConfig.Clusters) == 0 || len(newConfig.AuthInfos) == 0 {
 		fmt.Fprintln(o.out, "None cluster, cancel set")
 		return nil
 	}
+	// There is no way to tell which one to use among multiple entries.
 	if len(newConfig.Clusters) != 1 || len(newConfig.AuthInfos) != 1 {
-		return errors.New("Invalid edit config, the number of cluster and user should be one")
+		return fmt.Errorf("Invalid edit config, the number of cluster and user should be one, found %d clusters (%s) and %d users (%s)",
+			len(newConfig.Clusters), formatNames(getSortedKeys(newConfig.Clusters)),
+			len(newConfig.AuthInfos), formatNames(getSortedKeys(newConfig.AuthInfos)))
 	}
 
-	var cluster *clientcmdapi.Cluster
-	for _, c := range newConfig.Clusters {
-		cluster = c
-		break
+	clusterKey := getSortedKeys(newConfig.Clusters)[0]
+	o.warnRename("cluster", clusterKey)
+	cluster := newConfig.Clusters[clusterKey]
+
+	authInfoKey := getSortedKeys(newConfig.AuthInfos)[0]
+	o.warnRename("user", authInfoKey)
+	authInfo := newConfig.AuthInfos[authInfoKey]
+
+	// Editing may take a long time, so only lock when applying the result, and
+	// reload the config in case it was modified meanwhile.
+	return withConfigLock(o.configAccess, func() error {
+		return o.apply(cluster, authInfo)
+	})
+}
+
+// warnRename warns that the edited cluster or user is renamed. kubeswitch
+// always stores them under the context name, so that one cluster owns its own
+// entries, and deleting or renaming it does not affect others.
+func (o *setOptions) warnRename(kind, key string) {
+	if key == o.name {
+		return
 	}
+	fmt.Fprintf(o.out, "%s: the %s %q will be stored as %q, kubeswitch names the cluster and user after the context\n", color.YellowString("warning"), kind, key, o.name)
+}
 
-	var authInfo *clientcmdapi.AuthInfo
-	for _, a := range newConfig.AuthInfos {
-		authInfo = a
-		break
+func (o *setOptions) apply(cluster *clientcmdapi.Cluster, authInfo *clientcmdapi.AuthInfo) error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
 	}
 
-	ns := "default"
+	var ns string
+	var extensions map[string]runtime.Object
 	if ctx, ok := config.Contexts[o.name]; ok {
 		ns = ctx.Namespace
+		extensions = ctx.Extensions
+	} else if o.namespace == "" {
+		ns, err = o.getDefaultNamespace()
+		if err != nil {
+			return err
+		}
+	}
+	if o.namespace != "" {
+		ns = o.namespace
 	}
 
 	config.Clusters[o.name] = cluster
 	config.AuthInfos[o.name] = authInfo
 	config.Contexts[o.name] = &clientcmdapi.Context{
-		Cluster:   o.name,
-		AuthInfo:  o.name,
-		Namespace: ns,
+		Cluster:    o.name,
+		AuthInfo:   o.name,
+		Namespace:  ns,
+		Extensions: extensions,
+	}
+
+	if o.validate {
+		err = o.validateCluster(config)
+		if err != nil {
+			return err
+		}
 	}
 
 	fmt.Fprintf(o.out, "Set cluster %q done.\n", o.name)
-	err = clientcmd.ModifyConfig(o.configAccess, *config, true)
+	err = modifyConfig(o.configAccess, config)
 	if err != nil {
 		return fmt.Errorf("Write config: %w", err)
 	}
@@ -102,6 +205,119 @@ func (o *setOptions) run() error {
 	return nil
 }
 
+func (o *setOptions) getDefaultNamespace() (string, error) {
+	cfg, err := readConfig(o.configAccess)
+	if err != nil {
+		return "", err
+	}
+	if cfg.DefaultNamespace == "" {
+		return "default", nil
+	}
+	return executeNamespaceTemplate(cfg.DefaultNamespace, o.name)
+}
+
+func executeNamespaceTemplate(text, name string) (string, error) {
+	tmpl, err := template.New("defaultNamespace").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Parse defaultNamespace template: %w", err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct{ Name string }{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("Execute defaultNamespace template: %w", err)
+	}
+	ns := strings.TrimSpace(buf.String())
+	if ns == "" {
+		return "", errors.New("The defaultNamespace template renders an empty namespace")
+	}
+	return ns, nil
+}
+
+func (o *setOptions) updateFields() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, ok := config.Contexts[o.name]
+	if !ok {
+		return fmt.Errorf("Cannot find cluster %q", o.name)
+	}
+	cluster, ok := config.Clusters[ctx.Cluster]
+	if !ok {
+		return fmt.Errorf("Cannot find cluster %q for context %q", ctx.Cluster, o.name)
+	}
+
+	if o.tlsServerNameSet {
+		cluster.TLSServerName = o.tlsServerName
+	}
+	if o.proxyURLSet {
+		cluster.ProxyURL = o.proxyURL
+	}
+	if o.namespace != "" {
+		ctx.Namespace = o.namespace
+	}
+	if len(o.extensions) > 0 {
+		values := getContextExtensions(ctx)
+		for _, ext := range o.extensions {
+			key, value, _ := strings.Cut(ext, "=")
+			if value == "" {
+				delete(values, key)
+				continue
+			}
+			values[key] = value
+		}
+		err = setContextExtensions(ctx, values)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.validate {
+		err = o.validateCluster(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Write config: %w", err)
+	}
+	fmt.Fprintf(o.out, "Update cluster %q done.\n", o.name)
+
+	return nil
+}
+
+func (o *setOptions) validateCluster(config *clientcmdapi.Config) error {
+	ctx := config.Contexts[o.name]
+	cluster := config.Clusters[ctx.Cluster]
+
+	serverURL, err := url.Parse(cluster.Server)
+	if err != nil {
+		return fmt.Errorf("Invalid server URL %q: %w", cluster.Server, err)
+	}
+	if serverURL.Scheme != "https" && serverURL.Scheme != "http" {
+		return fmt.Errorf("Invalid server URL %q, the scheme should be https or http", cluster.Server)
+	}
+	if serverURL.Host == "" {
+		return fmt.Errorf("Invalid server URL %q, missing host", cluster.Server)
+	}
+
+	// The exec plugin may require interaction or cloud credentials, do not
+	// run it when setting cluster.
+	if authInfo, ok := config.AuthInfos[ctx.AuthInfo]; ok && authInfo.Exec != nil {
+		fmt.Fprintf(o.out, "Skip checking cluster %q with exec auth\n", o.name)
+		return nil
+	}
+
+	status := checkContext(config, o.name, 2*time.Second)
+	if status != statusOk {
+		fmt.Fprintf(o.out, "%s: cluster %q is %s\n", color.YellowString("warning"), o.name, status)
+	}
+	return nil
+}
+
 func (o *setOptions) getConfigToEdit(cfg *clientcmdapi.Config) *clientcmdapi.Config {
 	cluster, ok := cfg.Clusters[o.name]
 	if !ok {
@@ -128,15 +344,40 @@ func (o *setOptions) getConfigToEdit(cfg *clientcmdapi.Config) *clientcmdapi.Con
 	}
 }
 
-func (o *setOptions) edit(cfg *clientcmdapi.Config) (*clientcmdapi.Config, error) {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		return nil, errors.New("Missing env EDITOR to edit file")
+func (o *setOptions) getEditor() ([]string, error) {
+	return getEditor(o.configAccess, o.editor)
+}
+
+// editReferenceMarker separates the editing content and the reference, only
+// the content above it is parsed.
+const editReferenceMarker = "# ------ kubeswitch: the content below is for reference only and will be ignored ------"
+
+// getEditReference returns the full redacted config as comments.
+func getEditReference(config *clientcmdapi.Config) ([]byte, error) {
+	refConfig := config.DeepCopy()
+	redactConfig(refConfig)
+	data, err := clientcmd.Write(*refConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Encode reference config: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\n" + editReferenceMarker + "\n")
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		buf.WriteString("# " + line + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *setOptions) edit(cfg *clientcmdapi.Config, reference []byte) (*clientcmdapi.Config, error) {
+	editorArgs, err := o.getEditor()
+	if err != nil {
+		return nil, err
 	}
+	editor := editorArgs[0]
 	fmt.Fprintf(o.out, "Use editor %q to edit kube config content.\n", editor)
 
 	var data []byte
-	var err error
 	if cfg != nil {
 		data, err = clientcmd.Write(*cfg)
 		if err != nil {
@@ -156,6 +397,7 @@ func (o *setOptions) edit(cfg *clientcmdapi.Config) (*clientcmdapi.Config, error
 		return nil, err
 	}
 
+	data = append(data, reference...)
 	if len(data) > 0 {
 		buffer := bytes.NewBuffer(data)
 		_, err = io.Copy(file, buffer)
@@ -169,12 +411,17 @@ func (o *setOptions) edit(cfg *clientcmdapi.Config) (*clientcmdapi.Config, error
 		return nil, fmt.Errorf("Close temp file: %w", err)
 	}
 
-	cmd := exec.Command(editor, abs)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	err = cmd.Run()
+	err = runEditor(editorArgs, abs)
 	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("Cannot find editor %q, please check your editor config", editor)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && !o.noEditorCancel {
+			// Quit the editor with non-zero code (such as ":cq" in vim) means
+			// the user wants to cancel editing.
+			return nil, nil
+		}
 		return nil, fmt.Errorf("Use editor %q to edit temp file failed: %w", editor, err)
 	}
 
@@ -182,6 +429,9 @@ func (o *setOptions) edit(cfg *clientcmdapi.Config) (*clientcmdapi.Config, error
 	if err != nil {
 		return nil, fmt.Errorf("Read temp file after editing: %w", err)
 	}
+	if idx := bytes.Index(data, []byte(editReferenceMarker)); idx >= 0 {
+		data = data[:idx]
+	}
 
 	editedConfig, err := clientcmd.Load(data)
 	if err != nil {
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Shell starts a subshell with a temp kube config of the cluster, it is the
+// exec command running $SHELL, so the global config is never touched.
+func Shell(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &execOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "shell NAME [-n namespace]",
+		Short: "Start a subshell with a cluster without switching",
+
+		Args: cobra.ExactArgs(1),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts.name = args[0]
+			shell := os.Getenv("SHELL")
+			if shell == "" {
+				shell = "/bin/sh"
+			}
+			opts.args = []string{shell}
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Set the namespace of the cluster in the subshell")
+	_ = cmd.RegisterFlagCompletionFunc("namespace", completeShellNamespaceFunc)
+
+	return cmd
+}
+
+// completeShellNamespaceFunc completes the namespace of the cluster given by
+// the first arg rather than the current one.
+func completeShellNamespaceFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	items, _, err := listNamespaces(getCompletionConfigAccess(cmd), args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return items, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type showOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+	stdout       io.Writer
+
+	name   string
+	reveal bool
+}
+
+func Show(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &showOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "show [--reveal] [NAME]",
+		Short: "Show the kube config entry of cluster",
+
+		Args: cobra.MaximumNArgs(1),
+
+		ValidArgsFunction: completeContextFunc,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) >= 1 {
+				opts.name = args[0]
+			}
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.reveal, "reveal", false, "Show the secrets such as token and client key rather than redacting them")
+
+	return cmd
+}
+
+func (o *showOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	name := o.name
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name == "" {
+		return errors.New("No context selected")
+	}
+
+	// The remote contexts are shown as well, since they can be selected by
+	// the use command.
+	if _, ok := config.Contexts[name]; !ok {
+		remote, err := loadRemoteConfig(o.configAccess)
+		if err != nil {
+			return err
+		}
+		if remote != nil {
+			config, _, err = withRemoteContexts(config, remote)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := config.Contexts[name]; !ok {
+		return fmt.Errorf("Cannot find cluster %q", name)
+	}
+
+	showConfig := clientcmdapi.NewConfig()
+	err = mergeContext(showConfig, config, name, name)
+	if err != nil {
+		return err
+	}
+	showConfig.CurrentContext = name
+
+	if !o.reveal {
+		redactConfig(showConfig)
+	}
+
+	data, err := clientcmd.Write(*showConfig)
+	if err != nil {
+		return fmt.Errorf("Encode config for %q: %w", name, err)
+	}
+	_, err = o.stdout.Write(data)
+	return err
+}
+
+// redactConfig redacts the secrets in config in place, such as the token and
+// client key.
+func redactConfig(config *clientcmdapi.Config) {
+	clientcmdapi.ShortenConfig(config)
+	for _, authInfo := range config.AuthInfos {
+		if authInfo.Password != "" {
+			authInfo.Password = "REDACTED"
+		}
+		if authInfo.Exec != nil {
+			authInfo.Exec.Args = redactExecArgs(authInfo.Exec.Args)
+			// The env is often used to pass credentials, such as
+			// AWS_SECRET_ACCESS_KEY.
+			for i := range authInfo.Exec.Env {
+				authInfo.Exec.Env[i].Value = "REDACTED"
+			}
+		}
+		if authInfo.AuthProvider != nil {
+			// Such as id-token, refresh-token and client-secret.
+			for key := range authInfo.AuthProvider.Config {
+				authInfo.AuthProvider.Config[key] = "REDACTED"
+			}
+		}
+	}
+}
+
+// getShowPreview returns the fzf preview command to show the highlighted
+// cluster by kubeswitch itself, the kube config files are passed by env so
+// that the preview reads the same config.
+func getShowPreview(configAccess clientcmd.ConfigAccess) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("Get executable path: %w", err)
+	}
+	paths := strings.Join(configAccess.GetLoadingPrecedence(), string(filepath.ListSeparator))
+	return fmt.Sprintf("%s=%s %s show {2} --reveal=false", clientcmd.RecommendedConfigPathEnvVar,
+		shellQuote(paths), shellQuote(exe)), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
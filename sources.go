@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type configSource struct {
+	path   string
+	config *clientcmdapi.Config
+}
+
+func loadConfigSources(configAccess clientcmd.ConfigAccess) ([]*configSource, error) {
+	var sources []*configSource
+	for _, path := range configAccess.GetLoadingPrecedence() {
+		config, err := loadConfigFile(configAccess, path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("Load config file %q: %w", path, err)
+		}
+		sources = append(sources, &configSource{path: path, config: config})
+	}
+	return sources, nil
+}
+
+// The first file that defines a value wins when client-go merges multiple
+// kubeconfig files, so the owner is always the first match.
+func getContextSource(sources []*configSource, name string) *configSource {
+	for _, source := range sources {
+		if _, ok := source.config.Contexts[name]; ok {
+			return source
+		}
+	}
+	return nil
+}
+
+func getCurrentContextSource(sources []*configSource) *configSource {
+	for _, source := range sources {
+		if source.config.CurrentContext != "" {
+			return source
+		}
+	}
+	return nil
+}
+
+// writeCurrentContext sets the current-context in the file chosen by config
+// "currentContextFile" for multiple kubeconfig files. The current-context set
+// by the previous files is cleared, since the first one wins when merging.
+func writeCurrentContext(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config, name string) error {
+	sources, err := loadConfigSources(configAccess)
+	if err != nil {
+		return err
+	}
+	if len(sources) <= 1 {
+		config.CurrentContext = name
+		return modifyConfig(configAccess, config)
+	}
+
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return err
+	}
+	var target *configSource
+	switch cfg.CurrentContextFile {
+	case "", "owner":
+		target = getContextSource(sources, name)
+		if target == nil {
+			return fmt.Errorf("Cannot find the file defining context %q", name)
+		}
+	case "first":
+		target = sources[0]
+	default:
+		return fmt.Errorf("Invalid currentContextFile %q in config, should be owner or first", cfg.CurrentContextFile)
+	}
+
+	for _, source := range sources {
+		if source == target {
+			break
+		}
+		if source.config.CurrentContext == "" {
+			continue
+		}
+		source.config.CurrentContext = ""
+		err = clientcmd.WriteToFile(*source.config, source.path)
+		if err != nil {
+			return fmt.Errorf("Write config file %q: %w", source.path, err)
+		}
+	}
+
+	target.config.CurrentContext = name
+	err = clientcmd.WriteToFile(*target.config, target.path)
+	if err != nil {
+		return fmt.Errorf("Write config file %q: %w", target.path, err)
+	}
+	config.CurrentContext = name
+	return nil
+}
+
+// getDefiningFiles returns the files defining any of the contexts.
+func getDefiningFiles(sources []*configSource, names []string) []string {
+	var paths []string
+	for _, source := range sources {
+		for _, name := range names {
+			if _, ok := source.config.Contexts[name]; ok {
+				paths = append(paths, source.path)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// removeContextsFromSources removes the contexts, and the clusters and users
+// named after them, from all the kubeconfig files. ModifyConfig only removes
+// them from the first file defining them, the duplicates in other files would
+// show up again after merging.
+func removeContextsFromSources(configAccess clientcmd.ConfigAccess, names []string) error {
+	sources, err := loadConfigSources(configAccess)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		var changed bool
+		for _, name := range names {
+			if _, ok := source.config.Contexts[name]; !ok {
+				continue
+			}
+			delete(source.config.Contexts, name)
+			delete(source.config.AuthInfos, name)
+			delete(source.config.Clusters, name)
+			if source.config.CurrentContext == name {
+				source.config.CurrentContext = ""
+			}
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		err = clientcmd.WriteToFile(*source.config, source.path)
+		if err != nil {
+			return fmt.Errorf("Write config file %q: %w", source.path, err)
+		}
+	}
+	return nil
+}
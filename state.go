@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func getStatePath(configAccess clientcmd.ConfigAccess, name string) string {
+	filename := configAccess.GetDefaultFilename()
+	dir := filepath.Dir(filename)
+	return filepath.Join(dir, name)
+}
+
+func readState(configAccess clientcmd.ConfigAccess, name string, v any) error {
+	path := getStatePath(configAccess, name)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("Open state file %q: %w", name, err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	err = decoder.Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("Decode state file %q: %w", name, err)
+	}
+	return nil
+}
+
+func writeState(configAccess clientcmd.ConfigAccess, name string, v any) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Encode state file %q: %w", name, err)
+	}
+
+	path := getStatePath(configAccess, name)
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("Write state file %q: %w", name, err)
+	}
+	return nil
+}
+
+// writeSecretState is like writeState, but the file is only accessible by the
+// owner, the permission is fixed before writing if the file exists.
+func writeSecretState(configAccess clientcmd.ConfigAccess, name string, v any) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Encode state file %q: %w", name, err)
+	}
+
+	path := getStatePath(configAccess, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Open state file %q: %w", name, err)
+	}
+	defer file.Close()
+
+	err = file.Chmod(0600)
+	if err != nil {
+		return fmt.Errorf("Chmod state file %q: %w", name, err)
+	}
+	err = file.Truncate(0)
+	if err != nil {
+		return fmt.Errorf("Truncate state file %q: %w", name, err)
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("Write state file %q: %w", name, err)
+	}
+	return file.Close()
+}
+
+func renameStateKey[T any](configAccess clientcmd.ConfigAccess, name, oldKey, newKey string) error {
+	state := make(map[string]T)
+	err := readState(configAccess, name, &state)
+	if err != nil {
+		return err
+	}
+	value, ok := state[oldKey]
+	if !ok {
+		return nil
+	}
+	delete(state, oldKey)
+	state[newKey] = value
+	return writeState(configAccess, name, state)
+}
+
+type stackState map[string][]string
+
+func (s stackState) push(key, value string) {
+	s[key] = append(s[key], value)
+}
+
+func (s stackState) pop(key string) (string, bool) {
+	stack := s[key]
+	if len(stack) == 0 {
+		return "", false
+	}
+	value := stack[len(stack)-1]
+	stack = stack[:len(stack)-1]
+	if len(stack) == 0 {
+		delete(s, key)
+	} else {
+		s[key] = stack
+	}
+	return value, true
+}
+
+const maxHistory = 50
+
+type historyState map[string][]string
+
+func (s historyState) visit(key, value string) {
+	history := make([]string, 0, len(s[key])+1)
+	history = append(history, value)
+	for _, item := range s[key] {
+		if item != value {
+			history = append(history, item)
+		}
+	}
+	if len(history) > maxHistory {
+		history = history[:maxHistory]
+	}
+	s[key] = history
+}
+
+func sortByRecent(items, recent []string) []string {
+	rank := make(map[string]int, len(recent))
+	for i, item := range recent {
+		rank[item] = i
+	}
+
+	sorted := make([]string, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, iok := rank[sorted[i]]
+		rj, jok := rank[sorted[j]]
+		if iok && jok {
+			return ri < rj
+		}
+		return iok && !jok
+	})
+	return sorted
+}
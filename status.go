@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type statusOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+	stdout       io.Writer
+
+	output string
+}
+
+type statusInfo struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Server    string `json:"server"`
+
+	// The seconds until the client certificate expires, nil for exec or
+	// non-expiring credentials.
+	CredExpiresIn *int64 `json:"credExpiresIn"`
+
+	Bookmarks []string `json:"bookmarks"`
+}
+
+func Status(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &statusOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "status [-o json]",
+		Short: "Show the status of the current cluster",
+
+		Args: cobra.ExactArgs(0),
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			switch opts.output {
+			case "text", "json":
+			default:
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "The output format, one of text and json")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("text", "json"))
+
+	return cmd
+}
+
+func (o *statusOptions) run() error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	ctxName := config.CurrentContext
+	if ctxName == "" {
+		return errors.New("No context selected")
+	}
+	ctx, ok := config.Contexts[ctxName]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q", ctxName)
+	}
+
+	info := &statusInfo{
+		Context:   ctxName,
+		Namespace: ctx.Namespace,
+	}
+	if info.Namespace == "" {
+		info.Namespace = "default"
+	}
+	if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+		info.Server = cluster.Server
+	}
+	if authInfo, ok := config.AuthInfos[ctx.AuthInfo]; ok {
+		expiresAt, err := getCertExpiration(authInfo)
+		if err != nil {
+			return err
+		}
+		if !expiresAt.IsZero() {
+			seconds := int64(time.Until(expiresAt).Seconds())
+			info.CredExpiresIn = &seconds
+		}
+	}
+
+	bookmarks, err := readBookmarks(o.configAccess)
+	if err != nil {
+		return err
+	}
+	info.Bookmarks = bookmarks[ctxName]
+	if info.Bookmarks == nil {
+		info.Bookmarks = []string{}
+	}
+
+	if o.output == "json" {
+		encoder := json.NewEncoder(o.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	fmt.Fprintf(o.out, "Cluster:   %s\n", nameColor().Sprint(info.Context))
+	fmt.Fprintf(o.out, "Namespace: %s\n", nameColor().Sprint(info.Namespace))
+	fmt.Fprintf(o.out, "Server:    %s\n", info.Server)
+	if info.CredExpiresIn != nil {
+		expiresIn := time.Duration(*info.CredExpiresIn) * time.Second
+		if expiresIn <= 0 {
+			fmt.Fprintln(o.out, "Cert:      expired")
+		} else {
+			fmt.Fprintf(o.out, "Cert:      expires in %s\n", expiresIn)
+		}
+	}
+	if len(info.Bookmarks) > 0 {
+		fmt.Fprintf(o.out, "Bookmarks: %s\n", formatBookmarks(info.Bookmarks))
+	}
+	return nil
+}
+
+// getCertExpiration returns the NotAfter of the client certificate, zero if
+// the user has no client certificate.
+func getCertExpiration(authInfo *clientcmdapi.AuthInfo) (time.Time, error) {
+	data := authInfo.ClientCertificateData
+	if len(data) == 0 && authInfo.ClientCertificate != "" {
+		var err error
+		data, err = os.ReadFile(authInfo.ClientCertificate)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Read client certificate: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return time.Time{}, nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, errors.New("Invalid client certificate, expect PEM format")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Parse client certificate: %w", err)
+	}
+	return cert.NotAfter, nil
+}
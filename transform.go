@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// transformConfigAccess decrypts the encrypted kubeconfig (such as
+// "config.sops.yaml") in memory when reading, the modified config will be
+// encrypted again by modifyConfig.
+type transformConfigAccess struct {
+	clientcmd.ConfigAccess
+}
+
+func newConfigAccess(pathOptions *clientcmd.PathOptions) clientcmd.ConfigAccess {
+	return &transformConfigAccess{ConfigAccess: pathOptions}
+}
+
+func (a *transformConfigAccess) GetStartingConfig() (*clientcmdapi.Config, error) {
+	path, err := a.getEncryptedPath()
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return a.ConfigAccess.GetStartingConfig()
+	}
+	return loadConfigFile(a, path)
+}
+
+func (a *transformConfigAccess) getEncryptedPath() (string, error) {
+	paths := a.GetLoadingPrecedence()
+	for _, path := range paths {
+		if !isEncryptedConfig(path) {
+			continue
+		}
+		if len(paths) > 1 {
+			return "", fmt.Errorf("The encrypted kube config %q cannot be used with other files", path)
+		}
+		return path, nil
+	}
+	return "", nil
+}
+
+func (a *transformConfigAccess) writeEncrypted(path string, config *clientcmdapi.Config) error {
+	transform, err := getConfigTransform(a)
+	if err != nil {
+		return err
+	}
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return fmt.Errorf("Encode kube config: %w", err)
+	}
+	data, err = runTransform(transform.Encrypt, path, data)
+	if err != nil {
+		return fmt.Errorf("Encrypt kube config: %w", err)
+	}
+
+	// Write to a temp file first, a failed write should not break the
+	// encrypted file. Replace the target of the symlink rather than the link
+	// itself.
+	path = resolveSymlink(path)
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("Write encrypted kube config: %w", err)
+	}
+	return nil
+}
+
+func isEncryptedConfig(path string) bool {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, ".yaml")
+	name = strings.TrimSuffix(name, ".yml")
+	return strings.HasSuffix(name, ".sops") || strings.HasSuffix(name, ".enc")
+}
+
+func getConfigTransform(configAccess clientcmd.ConfigAccess) (*TransformConfig, error) {
+	cfg, err := readConfig(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Transform == nil || cfg.Transform.Decrypt == "" || cfg.Transform.Encrypt == "" {
+		return nil, errors.New("Missing transform decrypt and encrypt commands in config for the encrypted kube config")
+	}
+	return cfg.Transform, nil
+}
+
+func loadConfigFile(configAccess clientcmd.ConfigAccess, path string) (*clientcmdapi.Config, error) {
+	if !isEncryptedConfig(path) {
+		return clientcmd.LoadFromFile(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	transform, err := getConfigTransform(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	data, err = runTransform(transform.Decrypt, path, data)
+	if err != nil {
+		return nil, fmt.Errorf("Decrypt kube config: %w", err)
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range config.Clusters {
+		v.LocationOfOrigin = path
+	}
+	for _, v := range config.AuthInfos {
+		v.LocationOfOrigin = path
+	}
+	for _, v := range config.Contexts {
+		v.LocationOfOrigin = path
+	}
+	return config, nil
+}
+
+// runTransform runs the transform command, the data is passed by stdin and
+// the result is read from stdout. The "{path}" in command will be replaced by
+// the kube config path.
+func runTransform(command, path string, data []byte) ([]byte, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, fmt.Errorf("Parse transform command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Invalid transform command %q", command)
+	}
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, "{path}", path)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("Run transform command %q: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	trashDirname = ".kubeswitch_trash"
+
+	// Only keep the recently deleted clusters.
+	maxTrashItems = 20
+)
+
+type trashItem struct {
+	path string
+	name string
+	time time.Time
+}
+
+// moveToTrash saves the deleted clusters to the trash directory, each as a
+// standalone kube config, whose current-context is the deleted name. The raw
+// entries are saved as is, so that the broken clusters can be deleted too. The
+// trash files are encrypted if the kube config is encrypted.
+func moveToTrash(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config, names []string) error {
+	dir := getStatePath(configAccess, trashDirname)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return fmt.Errorf("Create trash dir: %w", err)
+	}
+
+	var encryptedPath string
+	if transformAccess, ok := configAccess.(*transformConfigAccess); ok {
+		encryptedPath, err = transformAccess.getEncryptedPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		trashConfig := clientcmdapi.NewConfig()
+		ctx := config.Contexts[name]
+		trashConfig.Contexts[name] = ctx.DeepCopy()
+		if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+			trashConfig.Clusters[ctx.Cluster] = cluster.DeepCopy()
+		}
+		if authInfo, ok := config.AuthInfos[ctx.AuthInfo]; ok {
+			trashConfig.AuthInfos[ctx.AuthInfo] = authInfo.DeepCopy()
+		}
+		trashConfig.CurrentContext = name
+
+		data, err := clientcmd.Write(*trashConfig)
+		if err != nil {
+			return fmt.Errorf("Encode trash config for %q: %w", name, err)
+		}
+		filename := fmt.Sprintf("%d_%s", now.UnixNano(), getExportFilename(name))
+		path := filepath.Join(dir, filename)
+		if encryptedPath != "" {
+			path = strings.TrimSuffix(path, ".yaml") + ".enc.yaml"
+			data, err = encryptTrash(configAccess, path, data)
+			if err != nil {
+				return err
+			}
+		}
+		err = os.WriteFile(path, data, 0600)
+		if err != nil {
+			return fmt.Errorf("Write trash file: %w", err)
+		}
+	}
+
+	items, err := listTrash(configAccess)
+	if err != nil {
+		return err
+	}
+	for i := maxTrashItems; i < len(items); i++ {
+		err = os.Remove(items[i].path)
+		if err != nil {
+			return fmt.Errorf("Remove trash file: %w", err)
+		}
+	}
+	return nil
+}
+
+// listTrash returns the trashed clusters, the most recently deleted first.
+func listTrash(configAccess clientcmd.ConfigAccess) ([]*trashItem, error) {
+	dir := getStatePath(configAccess, trashDirname)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Read trash dir: %w", err)
+	}
+
+	var items []*trashItem
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		nanos, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		config, err := loadConfigFile(configAccess, path)
+		if err != nil {
+			return nil, fmt.Errorf("Load trash file %q: %w", entry.Name(), err)
+		}
+		items = append(items, &trashItem{
+			path: path,
+			name: config.CurrentContext,
+			time: time.Unix(0, nanos),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].time.After(items[j].time)
+	})
+	return items, nil
+}
+
+type undeleteOptions struct {
+	configAccess clientcmd.ConfigAccess
+	out          io.Writer
+
+	name string
+}
+
+func Undelete(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	opts := &undeleteOptions{configAccess: configAccess, out: out}
+
+	cmd := &cobra.Command{
+		Use:   "undelete [--name NAME]",
+		Short: "Restore a recently deleted cluster",
+
+		Args: cobra.NoArgs,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return opts.run()
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.name, "name", "", "Restore the cluster as another name, if the original name is in use")
+
+	return cmd
+}
+
+func (o *undeleteOptions) run() error {
+	items, err := listTrash(o.configAccess)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.New("No recently deleted cluster")
+	}
+
+	now := time.Now()
+	options := make([]string, len(items))
+	for i, item := range items {
+		options[i] = fmt.Sprintf("%s\tdeleted %s ago", item.name, duration.HumanDuration(now.Sub(item.time)))
+	}
+	idx, err := searchInteractive(o.configAccess, options)
+	if err != nil {
+		return fmt.Errorf("Search fzf: %w", err)
+	}
+	item := items[idx]
+
+	// Only lock after selection, since fzf may take a long time.
+	return withConfigLock(o.configAccess, func() error {
+		return o.restore(item)
+	})
+}
+
+func (o *undeleteOptions) restore(item *trashItem) error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name := item.name
+	if o.name != "" {
+		name = o.name
+	}
+	if _, ok := config.Contexts[name]; ok {
+		return fmt.Errorf("The cluster %q already exists, please use --name to restore as another name", name)
+	}
+
+	trashConfig, err := loadConfigFile(o.configAccess, item.path)
+	if err != nil {
+		return fmt.Errorf("Load trash file: %w", err)
+	}
+	err = restoreFromTrash(config, trashConfig, item.name, name)
+	if err != nil {
+		return err
+	}
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Modify config: %w", err)
+	}
+
+	err = os.Remove(item.path)
+	if err != nil {
+		return fmt.Errorf("Remove trash file: %w", err)
+	}
+	fmt.Fprintf(o.out, "Restore cluster %s\n", nameColor().Sprint(name))
+	return nil
+}
+
+func encryptTrash(configAccess clientcmd.ConfigAccess, path string, data []byte) ([]byte, error) {
+	transform, err := getConfigTransform(configAccess)
+	if err != nil {
+		return nil, err
+	}
+	data, err = runTransform(transform.Encrypt, path, data)
+	if err != nil {
+		return nil, fmt.Errorf("Encrypt trash config: %w", err)
+	}
+	return data, nil
+}
+
+// restoreFromTrash adds the trashed entries back to config. The cluster and
+// user keep their original keys unless the keys are taken, then they are
+// named after the restored context.
+func restoreFromTrash(config, trashConfig *clientcmdapi.Config, srcName, name string) error {
+	ctx, ok := trashConfig.Contexts[srcName]
+	if !ok {
+		return fmt.Errorf("Cannot find context %q in trash", srcName)
+	}
+	ctx = ctx.DeepCopy()
+	// Otherwise ModifyConfig writes the new entries back to the trash file.
+	ctx.LocationOfOrigin = ""
+
+	if cluster, ok := trashConfig.Clusters[ctx.Cluster]; ok {
+		key := ctx.Cluster
+		if _, taken := config.Clusters[key]; taken {
+			key = name
+			if _, taken = config.Clusters[key]; taken {
+				return fmt.Errorf("The cluster entry %q already exists", key)
+			}
+		}
+		cluster = cluster.DeepCopy()
+		cluster.LocationOfOrigin = ""
+		config.Clusters[key] = cluster
+		ctx.Cluster = key
+	}
+	if authInfo, ok := trashConfig.AuthInfos[ctx.AuthInfo]; ok {
+		key := ctx.AuthInfo
+		if _, taken := config.AuthInfos[key]; taken {
+			key = name
+			if _, taken = config.AuthInfos[key]; taken {
+				return fmt.Errorf("The user entry %q already exists", key)
+			}
+		}
+		authInfo = authInfo.DeepCopy()
+		authInfo.LocationOfOrigin = ""
+		config.AuthInfos[key] = authInfo
+		ctx.AuthInfo = key
+	}
+
+	config.Contexts[name] = ctx
+	return nil
+}
@@ -1,77 +1,457 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+const useInfoTimeout = 3 * time.Second
+
 type useOptions struct {
 	configAccess clientcmd.ConfigAccess
 	out          io.Writer
+	stdout       io.Writer
+
+	name   string
+	output string
+
+	push    bool
+	pop     bool
+	history bool
+
+	thenNs bool
+
+	assemble bool
+	code     string
+
+	noPreview bool
 
-	name string
+	all bool
+
+	info bool
+
+	yes bool
+
+	// The cluster switched to, set by run.
+	selected string
+
+	restoreNs    bool
+	restoreNsSet bool
+
+	fromFd   int
+	fromFile string
 }
 
 func Use(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
-	opts := &useOptions{configAccess: configAccess, out: out}
+	opts := &useOptions{configAccess: configAccess, out: out, stdout: os.Stdout}
 
 	cmd := &cobra.Command{
-		Use:   "use [NAME]",
+		Use:   "use [--assemble | --code CODE] [NAME]",
 		Short: "Switch to a cluster",
 
 		Args: cobra.MaximumNArgs(1),
 
 		ValidArgsFunction: completeContextFunc,
 
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) >= 1 {
 				opts.name = args[0]
 			}
-			return opts.run()
+			opts.restoreNsSet = cmd.Flags().Changed("restore-ns")
+			if opts.output != "" && opts.output != "name" {
+				return fmt.Errorf("Unsupported output format %q", opts.output)
+			}
+			if opts.pop && (opts.push || opts.history || opts.name != "") {
+				return errors.New("The --pop flag cannot be used with --push, --history or cluster name")
+			}
+			if opts.history && opts.name != "" {
+				return errors.New("The --history flag cannot be used with cluster name")
+			}
+			if opts.assemble && (opts.pop || opts.history || opts.fromFd >= 0 || opts.fromFile != "") {
+				return errors.New("The --assemble flag cannot be used with --pop, --history, --from-fd or --from-file")
+			}
+			if opts.code != "" && (opts.assemble || opts.pop || opts.history || opts.fromFd >= 0 || opts.fromFile != "") {
+				return errors.New("The --code flag cannot be used with --assemble, --pop, --history, --from-fd or --from-file")
+			}
+			if opts.fromFd >= 0 || opts.fromFile != "" {
+				if opts.name != "" || opts.pop || opts.history {
+					return errors.New("The --from-fd and --from-file flags cannot be used with --pop, --history or cluster name")
+				}
+				if opts.fromFd >= 0 && opts.fromFile != "" {
+					return errors.New("The --from-fd and --from-file flags cannot be used together")
+				}
+				name, err := opts.readFrom()
+				if err != nil {
+					return err
+				}
+				opts.name = name
+			}
+			err := opts.run()
+			if err != nil {
+				return err
+			}
+			if opts.info {
+				opts.printInfo()
+			}
+			if opts.thenNs {
+				return newNsOptions(configAccess, out).run()
+			}
+			return nil
 		},
 	}
 
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.output, "output", "o", "", "Print the selected cluster to stdout, only support \"name\"")
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValuesFunc("name"))
+	flags.BoolVar(&opts.push, "push", false, "Push the current cluster to stack before switching")
+	flags.BoolVar(&opts.pop, "pop", false, "Pop a cluster from stack and switch to it")
+	flags.BoolVar(&opts.history, "history", false, "Select a cluster from the switch history")
+	flags.IntVar(&opts.fromFd, "from-fd", -1, "Read the cluster name from the file descriptor rather than fzf, for integrations")
+	flags.StringVar(&opts.fromFile, "from-file", "", "Read the cluster name from the file (or fifo) rather than fzf, for integrations")
+	flags.BoolVar(&opts.restoreNs, "restore-ns", false, "Restore the namespace last used in the cluster, override the config restoreNamespace")
+	flags.BoolVar(&opts.assemble, "assemble", false, "Assemble a new cluster from a cluster and a user selected by fzf, NAME is the name of the new context")
+	flags.StringVar(&opts.code, "code", "", "Import the cluster resolved from the pairing code and switch to it, NAME is the name of the new context")
+	flags.BoolVarP(&opts.all, "all", "a", false, "Also select from the disabled clusters")
+	flags.BoolVar(&opts.noPreview, "no-preview", false, "Do not preview the kube config of the highlighted cluster in fzf")
+	flags.BoolVarP(&opts.yes, "yes", "y", false, "Do not ask for confirmation when switching to production cluster")
+	flags.BoolVar(&opts.info, "info", false, "Print the server version and node count of the cluster after switching")
+	flags.BoolVar(&opts.thenNs, "then-ns", false, "Select a namespace for the new cluster after switching")
+
 	return cmd
 }
 
+// run selects the cluster without lock, since fzf and the confirmation may
+// take a long time, then switches to it with lock.
 func (o *useOptions) run() error {
 	config, err := o.configAccess.GetStartingConfig()
 	if err != nil {
 		return err
 	}
 
-	name, err := o.selectContext(config)
+	state, err := readNavState(o.configAccess)
+	if err != nil {
+		return err
+	}
+
+	// The remote contexts can be selected as well, they will be imported.
+	view := config
+	remote, err := loadRemoteConfig(o.configAccess)
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		view, _, err = withRemoteContexts(config, remote)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add the new cluster to the reloaded config, nil if it exists.
+	var importFn func(*clientcmdapi.Config) error
+	var importMsg string
+
+	var name string
+	switch {
+	case o.pop:
+		var ok bool
+		name, ok = state.pop()
+		if !ok {
+			return errors.New("The cluster stack is empty")
+		}
+		if _, ok = view.Contexts[name]; !ok {
+			return fmt.Errorf("Cannot find cluster %q", name)
+		}
+
+	case o.history:
+		name, err = o.selectHistory(view, state)
+
+	case o.assemble:
+		name, err = assembleContext(o.configAccess, config, o.name)
+		assembled := config
+		importFn = func(dst *clientcmdapi.Config) error {
+			return copyContextEntries(dst, assembled, name)
+		}
+		importMsg = "Assemble cluster %s\n"
+
+	case o.code != "":
+		name, err = importCode(o.configAccess, config, o.code, o.name)
+		imported := config
+		importFn = func(dst *clientcmdapi.Config) error {
+			return mergeContext(dst, imported, name, name)
+		}
+		importMsg = "Import cluster %s from code\n"
+
+	default:
+		name, err = o.selectContext(view, state)
+	}
+	if err != nil {
+		return err
+	}
+	if importFn == nil {
+		if _, ok := config.Contexts[name]; !ok {
+			importFn = func(dst *clientcmdapi.Config) error {
+				return mergeContext(dst, remote, name, name)
+			}
+			importMsg = "Import cluster %s from remote\n"
+		}
+	}
+
+	if name != config.CurrentContext && !o.yes {
+		// The assembled or imported context is only in config, the view is
+		// copied before it is added.
+		ctx, ok := config.Contexts[name]
+		if !ok {
+			ctx = view.Contexts[name]
+		}
+		err = o.confirmProduction(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return withConfigLock(o.configAccess, func() error {
+		return o.apply(name, importFn, importMsg)
+	})
+}
+
+// apply switches to the cluster, the config and state are reloaded in case
+// they were modified during selection.
+func (o *useOptions) apply(name string, importFn func(*clientcmdapi.Config) error, importMsg string) error {
+	config, err := o.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	state, err := readNavState(o.configAccess)
+	if err != nil {
+		return err
+	}
+	if o.pop {
+		popped, ok := state.pop()
+		if !ok || popped != name {
+			return errors.New("The cluster stack was modified by another kubeswitch process, please try again")
+		}
+	}
+
+	if importFn != nil {
+		if _, ok := config.Contexts[name]; ok {
+			return fmt.Errorf("The cluster %q already exists", name)
+		}
+		err = importFn(config)
+		if err != nil {
+			return err
+		}
+		err = modifyConfig(o.configAccess, config)
+		if err != nil {
+			return fmt.Errorf("Write config: %w", err)
+		}
+		fmt.Fprintf(o.out, importMsg, nameColor().Sprint(name))
+	} else if _, ok := config.Contexts[name]; !ok {
+		return fmt.Errorf("Cannot find cluster %q", name)
+	}
+
+	err = o.restoreNamespace(config, name)
 	if err != nil {
 		return err
 	}
 
 	lastName := config.CurrentContext
-	changed := lastName != name
-	config.CurrentContext = name
-	err = clientcmd.ModifyConfig(o.configAccess, *config, true)
+	err = writeCurrentContext(o.configAccess, config, name)
 	if err != nil {
 		return fmt.Errorf("Modify config: %w", err)
 	}
-	if changed {
-		err = o.saveLast(lastName)
+
+	state.switchTo(lastName, name, o.push)
+	err = writeNavState(o.configAccess, state)
+	if err != nil {
+		return fmt.Errorf("Save navigation state: %w", err)
+	}
+	recordAuditLog(o.out, o.configAccess, "use", name, lastName, name)
+
+	o.selected = name
+	fmt.Fprintf(o.out, "Switch to cluster %s\n", nameColor().Sprint(name))
+	if o.output == "name" {
+		fmt.Fprintln(o.stdout, name)
+	}
+	return nil
+}
+
+// printInfo prints the basic info of the cluster switched to, to confirm the
+// switch target. The switching is done, so the errors are only warned.
+func (o *useOptions) printInfo() {
+	warn := func(err error) {
+		fmt.Fprintf(o.out, "%s: cannot get cluster info: %v\n", color.YellowString("warning"), err)
+	}
+	client, err := newKubeClient(o.configAccess, o.selected)
+	if err != nil {
+		warn(err)
+		return
+	}
+
+	ctx, cancel := newTimeoutContext(context.Background(), useInfoTimeout)
+	defer cancel()
+	serverVersion, err := getServerVersion(ctx, client)
+	if err != nil {
+		warn(err)
+		return
+	}
+	fmt.Fprintf(o.out, "Server version: %s\n", serverVersion)
+	o.warnVersionSkew(serverVersion)
+
+	nodes, err := countNodes(ctx, client)
+	if err != nil {
+		// The user may not be allowed to list nodes.
+		fmt.Fprintln(o.out, "Nodes:          unknown")
+		return
+	}
+	fmt.Fprintf(o.out, "Nodes:          %d\n", nodes)
+}
+
+func (o *useOptions) confirmProduction(ctx *clientcmdapi.Context, name string) error {
+	cfg, err := readConfig(o.configAccess)
+	if err != nil {
+		return err
+	}
+	prod, err := isProductionContext(cfg, ctx, name)
+	if err != nil || !prod {
+		return err
+	}
+	ok, err := confirm(fmt.Sprintf("Switching to %s context %q. Continue?", color.RedString("PRODUCTION"), name))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Switching cancelled")
+	}
+	return nil
+}
+
+// warnVersionSkew is only a heads-up, the errors are warned as well.
+func (o *useOptions) warnVersionSkew(serverVersion string) {
+	cfg, err := readConfig(o.configAccess)
+	if err != nil {
+		fmt.Fprintf(o.out, "%s: %v\n", color.YellowString("warning"), err)
+		return
+	}
+	msg, err := checkVersionSkew(cfg.VersionSkew, serverVersion)
+	if err != nil {
+		fmt.Fprintf(o.out, "%s: %v\n", color.YellowString("warning"), err)
+		return
+	}
+	if msg != "" {
+		fmt.Fprintf(o.out, "%s: %s\n", color.YellowString("warning"), msg)
+	}
+}
+
+// restoreNamespace restores the namespace last used by kubeswitch in the
+// cluster, in case it was changed outside.
+func (o *useOptions) restoreNamespace(config *clientcmdapi.Config, name string) error {
+	restore := o.restoreNs
+	if !o.restoreNsSet {
+		cfg, err := readConfig(o.configAccess)
 		if err != nil {
-			return fmt.Errorf("Save last use: %w", err)
+			return err
 		}
+		restore = cfg.RestoreNamespace
+	}
+	if !restore {
+		return nil
 	}
 
-	fmt.Fprintf(o.out, "Switch to cluster %s\n", nameColor().Sprint(name))
+	history := make(historyState)
+	err := readState(o.configAccess, nsHistoryFilename, &history)
+	if err != nil {
+		return err
+	}
+	ctx := config.Contexts[name]
+	if len(history[name]) == 0 || history[name][0] == ctx.Namespace {
+		return nil
+	}
+
+	ns := history[name][0]
+	ctx.Namespace = ns
+	err = modifyConfig(o.configAccess, config)
+	if err != nil {
+		return fmt.Errorf("Restore namespace: %w", err)
+	}
+
+	applied := make(map[string]string)
+	err = readState(o.configAccess, nsAppliedFilename, &applied)
+	if err != nil {
+		return err
+	}
+	applied[name] = ns
+	err = writeState(o.configAccess, nsAppliedFilename, applied)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.out, "Restore namespace %s\n", nameColor().Sprint(ns))
 	return nil
 }
 
-func (o *useOptions) selectContext(config *clientcmdapi.Config) (string, error) {
+func (o *useOptions) readFrom() (string, error) {
+	var file *os.File
+	if o.fromFd >= 0 {
+		file = os.NewFile(uintptr(o.fromFd), fmt.Sprintf("fd%d", o.fromFd))
+		if file == nil {
+			return "", fmt.Errorf("Invalid file descriptor %d", o.fromFd)
+		}
+	} else {
+		var err error
+		file, err = os.Open(o.fromFile)
+		if err != nil {
+			return "", fmt.Errorf("Open selection file: %w", err)
+		}
+	}
+	defer file.Close()
+
+	// Only read the first line, the writer of a fifo may keep it open.
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("Read selection: %w", err)
+	}
+	name := strings.TrimSpace(line)
+	if name == "" {
+		return "", errors.New("Empty cluster name from selection input")
+	}
+	return name, nil
+}
+
+func (o *useOptions) selectHistory(config *clientcmdapi.Config, state *navState) (string, error) {
+	var names []string
+	for _, name := range state.recent(config.CurrentContext) {
+		if _, ok := config.Contexts[name]; ok {
+			names = append(names, name)
+		}
+	}
+	names, err := o.filterNames(names)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", errors.New("No cluster in history")
+	}
+
+	idx, err := o.searchInteractive(names)
+	if err != nil {
+		return "", fmt.Errorf("Search fzf: %w", err)
+	}
+	return names[idx], nil
+}
+
+func (o *useOptions) selectContext(config *clientcmdapi.Config, state *navState) (string, error) {
 	if len(config.Contexts) == 0 {
 		return "", errors.New("No cluster to use")
 	}
@@ -79,29 +459,46 @@ func (o *useOptions) selectContext(config *clientcmdapi.Config) (string, error)
 	if o.name != "" {
 		name := o.name
 		if o.name == "-" {
-			var err error
-			name, err = o.readLast()
-			if err != nil {
-				return "", fmt.Errorf("Read last name: %w", err)
-			}
+			name = state.last(config.CurrentContext)
 			if name == "" {
 				return "", errors.New("You have not switch to any cluster yet")
 			}
 		}
-		if _, ok := config.Contexts[name]; !ok {
-			return "", fmt.Errorf("Cannot find cluster %q", o.name)
+		if _, ok := config.Contexts[name]; ok {
+			return name, nil
+		}
+		if o.fromFd >= 0 || o.fromFile != "" {
+			// The integrations do their own selection, do not guess for them.
+			return "", fmt.Errorf("Cannot find cluster %q", name)
 		}
 
-		return name, nil
+		names, err := o.filterNames(getContextNames(config))
+		if err != nil {
+			return "", err
+		}
+		match, err := matchName(names, name)
+		if err != nil {
+			return "", err
+		}
+		if match == "" {
+			return "", fmt.Errorf("Cannot find cluster %q", o.name)
+		}
+		return match, nil
 	}
 
-	names := make([]string, 0, len(config.Contexts))
-	for name := range config.Contexts {
-		names = append(names, name)
+	names, err := o.filterNames(getContextNames(config))
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", errors.New("No cluster matches the context prefix")
+	}
+	if len(names) == 1 {
+		fmt.Fprintf(o.out, "Only one cluster %s, select it\n", nameColor().Sprint(names[0]))
+		return names[0], nil
 	}
-	sort.Strings(names)
 
-	idx, err := searchFzf(names)
+	idx, err := o.searchInteractive(names)
 	if err != nil {
 		return "", fmt.Errorf("Search fzf: %w", err)
 	}
@@ -109,25 +506,35 @@ func (o *useOptions) selectContext(config *clientcmdapi.Config) (string, error)
 	return names[idx], nil
 }
 
-func (o *useOptions) saveLast(name string) error {
-	path := o.getLastPath()
-	return os.WriteFile(path, []byte(name), 0644)
+// filterNames drops the clusters not matching the context prefix, and the
+// disabled clusters unless --all.
+func (o *useOptions) filterNames(names []string) ([]string, error) {
+	names, err := filterContextNames(o.configAccess, names)
+	if err != nil {
+		return nil, err
+	}
+	if o.all {
+		return names, nil
+	}
+	return filterDisabledContexts(o.configAccess, names)
 }
 
-func (o *useOptions) readLast() (string, error) {
-	path := o.getLastPath()
-	data, err := os.ReadFile(path)
+func (o *useOptions) searchInteractive(names []string) (int, error) {
+	if o.noPreview {
+		return searchInteractive(o.configAccess, names)
+	}
+	preview, err := getShowPreview(o.configAccess)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
-		}
-		return "", err
+		return 0, err
 	}
-	return string(data), nil
+	return searchInteractive(o.configAccess, names, "--preview="+preview)
 }
 
-func (o *useOptions) getLastPath() string {
-	filename := o.configAccess.GetDefaultFilename()
-	dir := filepath.Dir(filename)
-	return filepath.Join(dir, ".last_switch_cluster")
+func getContextNames(config *clientcmdapi.Config) []string {
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
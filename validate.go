@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func ValidateConfig(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validate the kubeswitch config and namespace alias files",
+
+		Args: cobra.NoArgs,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := readConfig(configAccess)
+			if err != nil {
+				return fmt.Errorf("Invalid config file %s: %w", getConfigPath(configAccess), err)
+			}
+			for i, discoverCfg := range cfg.Discover {
+				if _, ok := discoverProviders[discoverCfg.Type]; !ok {
+					return fmt.Errorf("Invalid config file %s: unknown discover type %q at index %d", getConfigPath(configAccess), discoverCfg.Type, i)
+				}
+			}
+			for _, pattern := range cfg.ProductionContexts {
+				_, err = regexp.Compile(pattern)
+				if err != nil {
+					return fmt.Errorf("Invalid config file %s: invalid productionContexts pattern %q: %w", getConfigPath(configAccess), pattern, err)
+				}
+			}
+			if cfg.VersionSkew != nil {
+				_, err = checkVersionSkew(cfg.VersionSkew, "v0.0.0")
+				if err != nil {
+					return fmt.Errorf("Invalid config file %s: %w", getConfigPath(configAccess), err)
+				}
+			}
+			switch cfg.NsAliasMerge {
+			case "", nsAliasMergeFirst, nsAliasMergeUnion, nsAliasMergeLongest:
+			default:
+				return fmt.Errorf("Invalid config file %s: invalid nsAliasMerge %q, should be first, union or longest", getConfigPath(configAccess), cfg.NsAliasMerge)
+			}
+			switch cfg.CurrentContextFile {
+			case "", "owner", "first":
+			default:
+				return fmt.Errorf("Invalid config file %s: invalid currentContextFile %q, should be owner or first", getConfigPath(configAccess), cfg.CurrentContextFile)
+			}
+			if cfg.DefaultNamespace != "" {
+				_, err = executeNamespaceTemplate(cfg.DefaultNamespace, "example")
+				if err != nil {
+					return fmt.Errorf("Invalid config file %s: %w", getConfigPath(configAccess), err)
+				}
+			}
+			fmt.Fprintf(out, "Config file %s is valid\n", getConfigPath(configAccess))
+
+			aliasPath, err := getNsAliasPath(configAccess)
+			if err != nil {
+				return err
+			}
+			entries, err := readNsAliasEntries(configAccess)
+			if err == nil {
+				err = validateNsAliasEntries(entries)
+			}
+			if err != nil {
+				return fmt.Errorf("Invalid alias file %s: %w", aliasPath, err)
+			}
+			fmt.Fprintf(out, "Alias file %s is valid\n", aliasPath)
+
+			return nil
+		},
+	}
+}
+
+// validateNsAliasEntries checks the namespace list of each alias entry. The
+// list items are namespace names, "-NAME" to exclude, and "re:PATTERN" or
+// "!re:PATTERN" to include or exclude by regex.
+func validateNsAliasEntries(entries []*nsAliasEntry) error {
+	for _, entry := range entries {
+		if len(entry.nsList) == 0 {
+			return fmt.Errorf("alias %q should be a non-empty list of namespaces", entry.prefix)
+		}
+		for _, item := range entry.nsList {
+			if pattern, ok := strings.CutPrefix(strings.TrimPrefix(item, "!"), "re:"); ok {
+				_, err := regexp.Compile(pattern)
+				if err != nil {
+					return fmt.Errorf("alias %q: invalid pattern %q: %w", entry.prefix, item, err)
+				}
+				continue
+			}
+			name := strings.TrimPrefix(item, "-")
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				return fmt.Errorf("alias %q: invalid namespace %q: %s", entry.prefix, item, strings.Join(errs, ", "))
+			}
+		}
+	}
+	return nil
+}
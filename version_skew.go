@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+const defaultMaxMinorSkew = 1
+
+// checkVersionSkew returns the warning message if the server version is out of
+// the skew from the reference, empty if not.
+func checkVersionSkew(cfg *VersionSkewConfig, serverVersion string) (string, error) {
+	if cfg == nil || cfg.Reference == "" {
+		return "", nil
+	}
+	reference, err := utilversion.ParseGeneric(cfg.Reference)
+	if err != nil {
+		return "", fmt.Errorf("Invalid versionSkew reference %q: %w", cfg.Reference, err)
+	}
+	server, err := utilversion.ParseGeneric(serverVersion)
+	if err != nil {
+		// The managed clusters may have custom version, cannot compare.
+		return "", nil
+	}
+
+	maxMinor := cfg.MaxMinor
+	if maxMinor <= 0 {
+		maxMinor = defaultMaxMinorSkew
+	}
+	if server.Major() != reference.Major() {
+		return fmt.Sprintf("the server version %s has different major version from %s", serverVersion, cfg.Reference), nil
+	}
+	skew := int(server.Minor()) - int(reference.Minor())
+	if skew > maxMinor || -skew > maxMinor {
+		return fmt.Sprintf("the server version %s is out of the supported skew (%d minor) from %s", serverVersion, maxMinor, cfg.Reference), nil
+	}
+	return "", nil
+}